@@ -10,17 +10,20 @@ import (
 	"github.com/phoenix-next/phoenix-server/utils"
 	"gorm.io/gorm"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"time"
+	"unicode/utf8"
 )
 
 // CreateContest
 // @Summary      创建比赛
-// @Description  组织管理员创建一个比赛，比赛包含指定题号的题目
+// @Description  组织管理员创建一个比赛，比赛包含指定题号的题目，比赛结束前这些题目对非管理员隐藏
 // @Tags         比赛模块
 // @Accept       json
 // @Produce      json
 // @Param        x-token  header    string         true  "token"
-// @Param        data     body      model.CreateContestQ  true  "组织ID，比赛名称，比赛简介，可读权限，开始时间，结束时间，题目列表"
+// @Param        data     body      model.CreateContestQ  true  "组织ID，比赛名称，比赛简介，可读权限，开始时间，结束时间，是否计分，赛后是否公开题目，考试模式设置，题目列表"
 // @Success      200      {object}  model.CommonA  "是否成功，返回信息"
 // @Router       /api/v1/contests [post]
 func CreateContest(c *gin.Context) {
@@ -28,41 +31,39 @@ func CreateContest(c *gin.Context) {
 	data := utils.BindJsonData(c, &model.CreateContestQ{}).(*model.CreateContestQ)
 	user := utils.SolveUser(c)
 	// 用户权限判定
-	for _, admin := range service.GetOrganizationAdmin(data.OrgID) {
-		if admin.UserID == user.ID {
-			// 创建比赛
-			contest := &model.Contest{
-				OrgID:     data.OrgID,
-				Profile:   data.Profile,
-				Name:      data.Name,
-				Readable:  data.Readable,
-				StartTime: data.StartTime,
-				EndTime:   data.EndTime}
-			global.DB.Create(&contest)
-			// 维护比赛 - 题目关系
-			for _, problemID := range data.ProblemIDs {
-				// 有题目不存在的情况
-				if _, notFound := service.GetProblemByID(problemID); notFound {
-					c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目列表中有题目不存在"})
-					return
-				}
-				// 维护关系
-				global.DB.Create(&model.ContestProblem{
-					ContestID: contest.ID,
-					ProblemID: problemID})
-			}
-			// 返回结果
-			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建比赛成功"})
-			return
-		}
+	if !service.IsOrganizationAdmin(user.ID, data.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "仅有组织管理员才能创建比赛"})
+		return
+	}
+	// 有题目不存在的情况
+	problems, ok := service.BuildContestProblems(data.ProblemIDs, data.Problems)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目列表中有题目不存在或重复，或编号重复、编号与标题过长"})
+		return
 	}
-	// 用户没有权限
-	c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "仅有组织管理员才能创建比赛"})
+	// 创建比赛，并维护比赛 - 题目关系
+	contest := model.Contest{
+		OrgID:           data.OrgID,
+		Profile:         data.Profile,
+		Name:            data.Name,
+		Readable:        data.Readable,
+		StartTime:       data.StartTime,
+		EndTime:         data.EndTime,
+		IsRated:         data.IsRated,
+		PublishProblems: data.PublishProblems,
+		IsExam:          data.IsExam,
+		ExamKickOld:     data.ExamKickOld,
+		AllowedIPs:      data.AllowedIPs}
+	if err := service.CreateContest(&contest, problems); err != nil {
+		global.LOG.Panic("CreateContest: create contest error")
+	}
+	// 返回结果
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建比赛成功"})
 }
 
 // GetContest
 // @Summary      获取比赛信息
-// @Description  获取一个比赛的详细信息，包括该比赛的名称以及包含题目等信息
+// @Description  获取一个比赛的详细信息，包括该比赛的名称以及包含题目等信息，用户必须有该比赛的读权限，比赛开始前题目列表仅对组织管理员可见
 // @Tags         比赛模块
 // @Accept       json
 // @Produce      json
@@ -84,29 +85,54 @@ func GetContest(c *gin.Context) {
 		c.JSON(http.StatusOK, model.GetContestA{Success: false, Message: "比赛不存在"})
 		return
 	}
-	// 获取比赛的所有题目
+	// 用户权限判定
+	if !service.JudgeContestReadPermission(user.ID, contest) {
+		c.JSON(http.StatusOK, model.GetContestA{Success: false, Message: "您对该比赛无可读权限"})
+		return
+	}
+	// 获取比赛的所有题目，比赛开始前题目列表仅对组织管理员可见
 	problems := make([]model.ContestProblem, 0)
-	global.DB.Where("contest_id = ?", contest.ID).Find(&problems)
+	if !time.Now().Before(contest.StartTime) || service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		problems = service.GetContestProblems(contest.ID)
+	}
 	// 获取是否通过的信息
-	resProblems := make([]model.ProblemT, 0)
+	resProblems := make([]model.ContestProblemT, 0)
 	for _, problem := range problems {
 		result := service.GetUserFinalJudge(user.ID, problem.ProblemID)
 		tmp, _ := service.GetProblemByID(problem.ProblemID)
-		resProblems = append(resProblems, model.ProblemT{
+		title := problem.Title
+		if title == "" {
+			title = tmp.Name
+		}
+		resProblems = append(resProblems, model.ContestProblemT{
 			ProblemID:   problem.ProblemID,
 			ProblemName: tmp.Name,
+			Label:       problem.Label,
+			Title:       title,
+			Position:    problem.Position,
+			Score:       problem.Score,
 			Difficulty:  tmp.Difficulty,
 			Result:      result,
 		})
 	}
+	// 获取当前用户的虚拟参赛信息
+	virtualStartTime, virtualEndTime := "", ""
+	if virtual, notFound := service.GetVirtualContest(user.ID, contest.ID); !notFound {
+		virtualStartTime = virtual.StartTime.Format("2006-01-02 15:04:05")
+		virtualEndTime = virtual.EndTime.Format("2006-01-02 15:04:05")
+	}
 	// 返回结果
 	c.JSON(http.StatusOK, model.GetContestA{
-		Success:   true,
-		Name:      contest.Name,
-		Profile:   contest.Profile,
-		StartTime: contest.StartTime.Format("2006-01-02 15:04:05"),
-		EndTime:   contest.EndTime.Format("2006-01-02 15:04:05"),
-		Problem:   resProblems})
+		Success:          true,
+		Name:             contest.Name,
+		Profile:          contest.Profile,
+		StartTime:        contest.StartTime.Format("2006-01-02 15:04:05"),
+		EndTime:          contest.EndTime.Format("2006-01-02 15:04:05"),
+		Problem:          resProblems,
+		IsRated:          contest.IsRated,
+		IsFinalized:      contest.IsFinalized,
+		VirtualStartTime: virtualStartTime,
+		VirtualEndTime:   virtualEndTime})
 }
 
 // UpdateContest
@@ -116,7 +142,7 @@ func GetContest(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        x-token  header    string                         true  "token"
-// @Param        data     body      model.UpdateContestQ  true  "比赛名称，比赛简介，比赛包含的题目ID"
+// @Param        data     body      model.UpdateContestQ  true  "比赛名称，比赛简介，是否计分(已结算积分后不可修改)，比赛包含的题目列表(编号，位置，分值，显示标题)"
 // @Success      200      {object}  model.CommonA         "是否成功，返回信息"
 // @Router       /api/v1/contests/{id} [put]
 func UpdateContest(c *gin.Context) {
@@ -133,23 +159,32 @@ func UpdateContest(c *gin.Context) {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛不存在"})
 		return
 	}
-	// 修改比赛的元数据
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(utils.SolveUser(c).ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 有题目不存在的情况
+	problems, ok := service.BuildContestProblems(data.ProblemIDs, data.Problems)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目列表中有题目不存在或重复，或编号重复、编号与标题过长"})
+		return
+	}
+	// 在一个事务中修改比赛的元数据与题目 - 比赛关系
 	contest.Name = data.Name
 	contest.Profile = data.Profile
-	global.DB.Save(&contest)
-	// 更新题目 - 比赛关系
-	global.DB.Where("contest_id = ?", contest.ID).Delete(&model.ContestProblem{})
-	for _, problemID := range data.ProblemIDs {
-		// 获得对应ID的题目
-		problem, notFound := service.GetProblemByID(problemID)
-		if notFound {
-			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目列表中有题目不存在"})
+	if data.IsRated != nil {
+		// 已结算积分的比赛不能再修改是否计分
+		if contest.IsFinalized && *data.IsRated != contest.IsRated {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "该比赛已结算积分"})
 			return
 		}
-		// 维护关系
-		global.DB.Create(&model.ContestProblem{
-			ContestID: contest.ID,
-			ProblemID: problem.ID})
+		contest.IsRated = *data.IsRated
+	}
+	if err = service.UpdateContest(&contest, problems); err != nil {
+		global.LOG.Warn("UpdateContest: update contest error")
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "修改比赛信息失败"})
+		return
 	}
 	// 返回响应
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "已修改比赛信息"})
@@ -184,8 +219,14 @@ func DeleteContest(c *gin.Context) {
 		if user.ID == admin.UserID {
 			// 维护问题 - 比赛关系
 			global.DB.Where("contest_id = ?", contest.ID).Delete(&model.ContestProblem{})
+			// 删除比赛答疑
+			global.DB.Where("contest_id = ?", contest.ID).Delete(&model.Clarification{})
+			global.DB.Where("contest_id = ?", contest.ID).Delete(&model.ClarificationRead{})
 			// 删除比赛元数据
 			global.DB.Delete(&contest)
+			if contest.IsExam {
+				service.InvalidateExamCache()
+			}
 			// 返回响应
 			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "已删除比赛"})
 			return
@@ -255,6 +296,801 @@ func GetContestList(c *gin.Context) {
 		ContestList: slicedContests})
 }
 
+// StartVirtualContest
+// @Summary      开始虚拟参赛
+// @Description  用户在比赛结束后虚拟参加该比赛，获得与原比赛时长相同的个人时间窗口，仅窗口内的提交计入虚拟成绩
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                     true  "token"
+// @Param        id       path      int                        true  "比赛ID"
+// @Success      200      {object}  model.StartVirtualContestA  "是否成功，返回信息，虚拟参赛开始时间，虚拟参赛结束时间"
+// @Router       /api/v1/contests/{id}/virtual [post]
+func StartVirtualContest(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.JudgeContestReadPermission(user.ID, contest) {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "您对该比赛无可读权限"})
+		return
+	}
+	// 比赛尚未结束的情况
+	now := time.Now()
+	if now.Before(contest.EndTime) {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "比赛结束后才能虚拟参赛"})
+		return
+	}
+	// 已经正式参赛或虚拟参赛的情况
+	if service.HasUserJoinedContest(user.ID, contest) {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "您已正式参加过该比赛"})
+		return
+	}
+	if _, notFound = service.GetVirtualContest(user.ID, contest.ID); !notFound {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "您已虚拟参加过该比赛"})
+		return
+	}
+	// 创建虚拟参赛记录
+	virtual := model.VirtualContest{
+		UserID:    user.ID,
+		ContestID: contest.ID,
+		StartTime: now,
+		EndTime:   now.Add(contest.EndTime.Sub(contest.StartTime))}
+	if err = service.CreateVirtualContest(&virtual); utils.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusOK, model.StartVirtualContestA{Success: false, Message: "您已虚拟参加过该比赛"})
+		return
+	} else if err != nil {
+		global.LOG.Panic("StartVirtualContest: create virtual contest error")
+	}
+	// 返回响应
+	c.JSON(http.StatusOK, model.StartVirtualContestA{
+		Success:   true,
+		Message:   "已开始虚拟参赛",
+		StartTime: virtual.StartTime.Format("2006-01-02 15:04:05"),
+		EndTime:   virtual.EndTime.Format("2006-01-02 15:04:05")})
+}
+
+// GetContestStandings
+// @Summary      获取比赛榜单
+// @Description  获取比赛当前的榜单；若用户虚拟参加了该比赛，则返回原比赛在相同已进行时间时的榜单，并将用户的虚拟成绩置于其中
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                      true  "token"
+// @Param        id       path      int                         true  "比赛ID"
+// @Success      200      {object}  model.GetContestStandingsA  "是否成功，返回信息，榜单对应的已进行时间，榜单"
+// @Router       /api/v1/contests/{id}/standings [get]
+func GetContestStandings(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetContestStandingsA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetContestStandingsA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.JudgeContestReadPermission(user.ID, contest) {
+		c.JSON(http.StatusOK, model.GetContestStandingsA{Success: false, Message: "您对该比赛无可读权限"})
+		return
+	}
+	// 用户虚拟参赛的情况，返回相同已进行时间时的历史榜单
+	duration := contest.EndTime.Sub(contest.StartTime)
+	if virtual, notFound := service.GetVirtualContest(user.ID, contest.ID); !notFound {
+		elapsed := time.Since(virtual.StartTime)
+		if elapsed > duration {
+			elapsed = duration
+		}
+		standings := service.GetContestStandings(contest, elapsed)
+		standings = append(standings, service.GetVirtualStanding(virtual, service.GetContestProblemIDs(contest.ID), elapsed))
+		service.RankStandings(standings)
+		c.JSON(http.StatusOK, model.GetContestStandingsA{
+			Success:   true,
+			Elapsed:   int(elapsed.Minutes()),
+			Standings: standings})
+		return
+	}
+	// 比赛尚未开始的情况
+	elapsed := time.Since(contest.StartTime)
+	if elapsed < 0 {
+		c.JSON(http.StatusOK, model.GetContestStandingsA{Success: false, Message: "比赛尚未开始"})
+		return
+	}
+	if elapsed > duration {
+		elapsed = duration
+	}
+	// 返回响应
+	c.JSON(http.StatusOK, model.GetContestStandingsA{
+		Success:   true,
+		Elapsed:   int(elapsed.Minutes()),
+		Standings: service.GetContestStandings(contest, elapsed)})
+}
+
+// FinalizeContest
+// @Summary      结算比赛积分
+// @Description  组织管理员在计分比赛结束后，根据最终榜单结算参赛者的积分，每场比赛只能结算一次
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "比赛ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/contests/{id}/finalization [post]
+func FinalizeContest(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 比赛状态判定
+	if !contest.IsRated {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "该比赛不计分"})
+		return
+	}
+	if time.Now().Before(contest.EndTime) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛尚未结束"})
+		return
+	}
+	if contest.IsFinalized {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "该比赛已结算积分"})
+		return
+	}
+	// 结算积分
+	if err = service.FinalizeContestRating(contest); errors.Is(err, service.ErrContestFinalized) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "该比赛已结算积分"})
+		return
+	} else if err != nil {
+		global.LOG.Warn("FinalizeContest: finalize contest rating error")
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "结算积分失败"})
+		return
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "结算积分成功"})
+}
+
+// CreateContestExport
+// @Summary      创建比赛归档
+// @Description  组织管理员在比赛结束后创建比赛归档任务，归档在后台生成，包含最终榜单(CSV与JSON)、所有提交的元数据与代码、比赛使用的题目文件
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                      true  "token"
+// @Param        id       path      int                         true  "比赛ID"
+// @Success      200      {object}  model.CreateContestExportA  "是否成功，返回信息，归档任务ID"
+// @Router       /api/v1/contests/{id}/exports [post]
+func CreateContestExport(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CreateContestExportA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CreateContestExportA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.CreateContestExportA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 比赛尚未结束的情况
+	if time.Now().Before(contest.EndTime) {
+		c.JSON(http.StatusOK, model.CreateContestExportA{Success: false, Message: "比赛尚未结束"})
+		return
+	}
+	// 创建归档任务，并在后台生成归档
+	export := model.ContestExport{ContestID: contest.ID, CreatorID: user.ID}
+	if err = global.DB.Create(&export).Error; err != nil {
+		global.LOG.Panic("CreateContestExport: create contest export error")
+	}
+	go service.RunContestExport(export, contest)
+	c.JSON(http.StatusOK, model.CreateContestExportA{Success: true, Message: "已开始生成比赛归档", ExportID: export.ID})
+}
+
+// GetContestExport
+// @Summary      获取比赛归档状态
+// @Description  组织管理员获取比赛归档任务的状态，归档生成完成后返回下载路径(0 生成中，1 已完成，2 生成失败)
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token   header    string                   true  "token"
+// @Param        id        path      int                      true  "比赛ID"
+// @Param        exportID  path      int                      true  "归档任务ID"
+// @Success      200       {object}  model.GetContestExportA  "是否成功，返回信息，归档状态，下载路径，创建时间"
+// @Router       /api/v1/contests/{id}/exports/{exportID} [get]
+func GetContestExport(c *gin.Context) {
+	// 获取归档任务
+	export, ok := service.GetContestExportFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetContestExportA{Success: false, Message: "归档不存在或用户没有管理员权限"})
+		return
+	}
+	// 返回响应
+	downloadPath := ""
+	if export.Status == 1 {
+		downloadPath = "api/v1/contests/" + strconv.FormatUint(export.ContestID, 10) + "/exports/" + strconv.FormatUint(export.ID, 10) + "/file"
+	}
+	c.JSON(http.StatusOK, model.GetContestExportA{
+		Success:      true,
+		Status:       export.Status,
+		DownloadPath: downloadPath,
+		CreatedTime:  export.CreatedTime.Format("2006-01-02 15:04:05")})
+}
+
+// DownloadContestExport
+// @Summary      下载比赛归档
+// @Description  组织管理员下载已生成完成的比赛归档zip文件
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      application/zip
+// @Param        x-token   header    string         true  "token"
+// @Param        id        path      int            true  "比赛ID"
+// @Param        exportID  path      int            true  "归档任务ID"
+// @Success      200       {file}    file           "比赛归档文件"
+// @Router       /api/v1/contests/{id}/exports/{exportID}/file [get]
+func DownloadContestExport(c *gin.Context) {
+	// 获取归档任务
+	export, ok := service.GetContestExportFromParam(c)
+	if !ok || export.Status != 1 {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "归档不存在或尚未生成完成"})
+		return
+	}
+	// 返回归档文件
+	filename := service.GetContestExportFileName(export)
+	c.FileAttachment(filepath.Join(global.VP.GetString("export_path"), filename), filename)
+}
+
+// CloneContest
+// @Summary      复制比赛
+// @Description  组织管理员复制一个比赛的设置与题目列表，以新的名称与时间创建比赛
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "被复制的比赛ID"
+// @Param        data     body      model.CloneContestQ  true  "新比赛名称，开始时间，结束时间"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/contests/{id}/clone [post]
+func CloneContest(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CloneContestQ{}).(*model.CloneContestQ)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 比赛名称或时间非法的情况
+	if data.Name == "" || utf8.RuneCountInString(data.Name) > 32 {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛名称为空或过长"})
+		return
+	}
+	if !data.EndTime.After(data.StartTime) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛结束时间需晚于开始时间"})
+		return
+	}
+	// 复制比赛设置与题目列表
+	problems := service.GetContestProblems(contest.ID)
+	for i := range problems {
+		problems[i].ID = 0
+	}
+	clone := model.Contest{
+		OrgID:           contest.OrgID,
+		Name:            data.Name,
+		Profile:         contest.Profile,
+		Readable:        contest.Readable,
+		IsRated:         contest.IsRated,
+		PublishProblems: contest.PublishProblems,
+		IsExam:          contest.IsExam,
+		ExamKickOld:     contest.ExamKickOld,
+		AllowedIPs:      contest.AllowedIPs,
+		StartTime:       data.StartTime,
+		EndTime:         data.EndTime}
+	if err = service.CreateContest(&clone, problems); err != nil {
+		global.LOG.Panic("CloneContest: create contest error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "复制比赛成功"})
+}
+
+// CreateContestTemplate
+// @Summary      创建周期性比赛模板
+// @Description  组织管理员创建一个每周举行的比赛模板，服务器将自动创建即将举行的比赛，并从题目池中按顺序轮换选取题目
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                        true  "token"
+// @Param        id       path      int                           true  "组织ID"
+// @Param        data     body      model.CreateContestTemplateQ  true  "比赛名称前缀，比赛简介，可读权限，是否计分，星期，开始时刻，结束时刻，每场题目数量，题目池"
+// @Success      200      {object}  model.CommonA                 "是否成功，返回信息"
+// @Router       /api/v1/organizations/{id}/templates [post]
+func CreateContestTemplate(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CreateContestTemplateQ{}).(*model.CreateContestTemplateQ)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, id) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 模板名称非法的情况
+	if data.Name == "" || utf8.RuneCountInString(data.Name) > service.ContestTemplateNameMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "模板名称为空或过长"})
+		return
+	}
+	// 模板时间非法的情况
+	startMinute, ok1 := service.ParseClock(data.StartTime)
+	endMinute, ok2 := service.ParseClock(data.EndTime)
+	if !ok1 || !ok2 || endMinute <= startMinute || data.Weekday < 0 || data.Weekday > 6 {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛时间非法"})
+		return
+	}
+	// 题目池非法的情况
+	if _, ok := service.BuildContestProblems(data.ProblemIDs, nil); !ok || len(data.ProblemIDs) == 0 || data.ProblemCount <= 0 {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目池为空，或有题目不存在或重复"})
+		return
+	}
+	// 创建模板，并立即创建下一场比赛
+	template := model.ContestTemplate{
+		OrgID:        id,
+		CreatorID:    user.ID,
+		Name:         data.Name,
+		Profile:      data.Profile,
+		Readable:     data.Readable,
+		IsRated:      data.IsRated,
+		Weekday:      data.Weekday,
+		StartMinute:  startMinute,
+		Duration:     endMinute - startMinute,
+		ProblemCount: data.ProblemCount}
+	if err = service.CreateContestTemplate(&template, data.ProblemIDs); err != nil {
+		global.LOG.Panic("CreateContestTemplate: create contest template error")
+	}
+	if err = service.CreateContestFromTemplate(template, service.GetNextTemplateTime(template, time.Now())); err != nil {
+		global.LOG.Warn("CreateContestTemplate: create contest from template error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建比赛模板成功"})
+}
+
+// GetContestTemplate
+// @Summary      获取周期性比赛模板
+// @Description  组织管理员获取组织中所有的周期性比赛模板
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                     true  "token"
+// @Param        id       path      int                        true  "组织ID"
+// @Success      200      {object}  model.GetContestTemplateA  "是否成功，返回信息，比赛模板列表"
+// @Router       /api/v1/organizations/{id}/templates [get]
+func GetContestTemplate(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetContestTemplateA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, id) {
+		c.JSON(http.StatusOK, model.GetContestTemplateA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 获取比赛模板
+	templates := make([]model.ContestTemplateT, 0)
+	for _, template := range service.GetOrganizationContestTemplates(id) {
+		problemIDs := make([]uint64, 0)
+		for _, problem := range service.GetContestTemplateProblems(template.ID) {
+			problemIDs = append(problemIDs, problem.ProblemID)
+		}
+		templates = append(templates, model.ContestTemplateT{
+			ID:           template.ID,
+			Name:         template.Name,
+			Profile:      template.Profile,
+			Readable:     template.Readable,
+			IsRated:      template.IsRated,
+			Weekday:      template.Weekday,
+			StartTime:    service.FormatClock(template.StartMinute),
+			EndTime:      service.FormatClock(template.StartMinute + template.Duration),
+			ProblemCount: template.ProblemCount,
+			ProblemIDs:   problemIDs,
+			Count:        template.Count,
+			NextTime:     service.GetNextTemplateTime(template, time.Now()).Format("2006-01-02 15:04:05")})
+	}
+	c.JSON(http.StatusOK, model.GetContestTemplateA{Success: true, Templates: templates})
+}
+
+// DeleteContestTemplate
+// @Summary      删除周期性比赛模板
+// @Description  组织管理员删除一个周期性比赛模板，已创建的比赛不受影响
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token     header    string         true  "token"
+// @Param        id          path      int            true  "组织ID"
+// @Param        templateID  path      int            true  "比赛模板ID"
+// @Success      200         {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/organizations/{id}/templates/{templateID} [delete]
+func DeleteContestTemplate(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	tid, err2 := strconv.ParseUint(c.Param("templateID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛模板的存在性判定
+	template, notFound := service.GetContestTemplateByID(tid)
+	if notFound || template.OrgID != id {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛模板不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, id) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 删除比赛模板
+	if err := service.DeleteContestTemplate(&template); err != nil {
+		global.LOG.Panic("DeleteContestTemplate: delete contest template error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除比赛模板成功"})
+}
+
+// StartExamSession
+// @Summary      开始考试会话
+// @Description  考生在考试开始前或考试期间开始考试会话，考试期间的所有请求需在x-exam-session请求头中携带返回的会话密钥；若已有其他设备的活动会话，则按比赛设置拒绝新设备或踢出原设备
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token         header    string                   true   "token"
+// @Param        x-exam-session  header    string                   false  "原有的考试会话密钥，用于在原设备上继续考试"
+// @Param        id              path      int                      true   "比赛ID"
+// @Success      200             {object}  model.StartExamSessionA  "是否成功，返回信息，考试会话密钥"
+// @Router       /api/v1/contests/{id}/sessions [post]
+func StartExamSession(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound || !contest.IsExam {
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "考试不存在"})
+		return
+	}
+	// 考生身份判定
+	if !service.IsExamParticipant(user.ID, contest) {
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "您不是该考试的考生"})
+		return
+	}
+	if !time.Now().Before(contest.EndTime) {
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "考试已结束"})
+		return
+	}
+	// IP范围判定
+	if !utils.IsIPAllowed(c.ClientIP(), contest.AllowedIPs) {
+		service.CreateExamLog(contest.ID, user.ID, c.ClientIP(), c.Request.URL.Path, "IP不在考试允许的范围内")
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "IP不在考试允许的范围内"})
+		return
+	}
+	// 已有其他设备的活动会话，且比赛设置为拒绝新设备的情况
+	session, notFound := service.GetExamSession(contest.ID, user.ID)
+	if !notFound && session.Key == c.GetHeader("x-exam-session") {
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: true, Message: "已在原设备上继续考试", SessionKey: session.Key})
+		return
+	}
+	if !notFound && service.IsExamSessionActive(session) && !contest.ExamKickOld {
+		service.CreateExamLog(contest.ID, user.ID, c.ClientIP(), c.Request.URL.Path, "已在其他设备登录，拒绝新设备")
+		c.JSON(http.StatusOK, model.StartExamSessionA{Success: false, Message: "您已在其他设备上开始考试"})
+		return
+	}
+	if !notFound && service.IsExamSessionActive(session) {
+		service.CreateExamLog(contest.ID, user.ID, session.IP, c.Request.URL.Path, "已在其他设备登录，踢出原设备")
+	}
+	// 创建新的会话，原有会话随之失效
+	session.ContestID, session.UserID = contest.ID, user.ID
+	session.Key, session.IP, session.LastActiveTime = service.GenerateExamSessionKey(), c.ClientIP(), time.Now()
+	if err = service.SaveExamSession(&session); err != nil {
+		global.LOG.Panic("StartExamSession: save exam session error")
+	}
+	c.JSON(http.StatusOK, model.StartExamSessionA{Success: true, Message: "已开始考试会话", SessionKey: session.Key})
+}
+
+// GetExamLog
+// @Summary      获取考试拦截记录
+// @Description  组织管理员获取考试期间所有被拦截的访问记录，包括IP不符、多设备登录、访问教程与论坛等
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string             true  "token"
+// @Param        id       path      int                true  "比赛ID"
+// @Success      200      {object}  model.GetExamLogA  "是否成功，返回信息，拦截记录列表"
+// @Router       /api/v1/contests/{id}/exam-logs [get]
+func GetExamLog(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetExamLogA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetExamLogA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.GetExamLogA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 获取拦截记录
+	logs := service.GetExamLogs(contest.ID)
+	userIDs := make([]uint64, 0)
+	for _, log := range logs {
+		userIDs = append(userIDs, log.UserID)
+	}
+	users := service.GetUsersByIDs(userIDs)
+	finalLogs := make([]model.ExamLogT, 0)
+	for _, log := range logs {
+		finalLogs = append(finalLogs, model.ExamLogT{
+			UserID:      log.UserID,
+			UserName:    users[log.UserID].Name,
+			IP:          log.IP,
+			Path:        log.Path,
+			Reason:      log.Reason,
+			CreatedTime: log.CreatedTime.Format("2006-01-02 15:04:05")})
+	}
+	c.JSON(http.StatusOK, model.GetExamLogA{Success: true, Logs: finalLogs})
+}
+
+// CreateClarification
+// @Summary      创建比赛答疑
+// @Description  参赛者在比赛进行中就比赛题目提问；组织管理员则发布面向所有参赛者的公告
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                      true  "token"
+// @Param        id       path      int                         true  "比赛ID"
+// @Param        data     body      model.CreateClarificationQ  true  "题目ID(针对整场比赛则为0)，提问或公告内容"
+// @Success      200      {object}  model.CommonA               "是否成功，返回信息"
+// @Router       /api/v1/contests/{id}/clarifications [post]
+func CreateClarification(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CreateClarificationQ{}).(*model.CreateClarificationQ)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 内容为空的情况
+	if data.Content == "" {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "内容不能为空"})
+		return
+	}
+	// 题目不属于该比赛的情况
+	if data.ProblemID != 0 {
+		found := false
+		for _, problemID := range service.GetContestProblemIDs(contest.ID) {
+			found = found || problemID == data.ProblemID
+		}
+		if !found {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛中不存在该题目"})
+			return
+		}
+	}
+	// 组织管理员发布公告
+	if service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		clarification := model.Clarification{
+			ContestID:      contest.ID,
+			ProblemID:      data.ProblemID,
+			CreatorID:      user.ID,
+			Answer:         data.Content,
+			AnswererID:     user.ID,
+			IsPublic:       true,
+			IsAnnouncement: true}
+		global.DB.Create(&clarification)
+		service.PublishClarificationEvent(clarification)
+		c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "发布公告成功"})
+		return
+	}
+	// 参赛者权限判定
+	if !service.JudgeContestReadPermission(user.ID, contest) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您对该比赛无可读权限"})
+		return
+	}
+	if !service.IsContestRunning(contest) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "仅能在比赛进行中提问"})
+		return
+	}
+	// 参赛者提问
+	clarification := model.Clarification{
+		ContestID: contest.ID,
+		ProblemID: data.ProblemID,
+		CreatorID: user.ID,
+		Question:  data.Content}
+	global.DB.Create(&clarification)
+	service.PublishClarificationEvent(clarification)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "提问成功"})
+}
+
+// AnswerClarification
+// @Summary      回答比赛答疑
+// @Description  组织管理员回答参赛者的提问，可以仅回复提问者，也可以向所有参赛者广播
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token          header    string                      true  "token"
+// @Param        id               path      int                         true  "比赛ID"
+// @Param        clarificationID  path      int                         true  "答疑ID"
+// @Param        data             body      model.AnswerClarificationQ  true  "回答内容，是否广播"
+// @Success      200              {object}  model.CommonA               "是否成功，返回信息"
+// @Router       /api/v1/contests/{id}/clarifications/{clarificationID} [put]
+func AnswerClarification(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.AnswerClarificationQ{}).(*model.AnswerClarificationQ)
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	cid, err2 := strconv.ParseUint(c.Param("clarificationID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛与答疑的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	clarification, notFound := service.GetClarificationByID(cid)
+	if notFound || clarification.ContestID != contest.ID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "答疑不存在"})
+		return
+	}
+	// 用户权限判定
+	if !service.IsOrganizationAdmin(user.ID, contest.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 公告不是提问，无法回答
+	if clarification.IsAnnouncement {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "公告无法回答"})
+		return
+	}
+	// 回答答疑
+	if err := global.DB.Model(&clarification).Updates(map[string]interface{}{
+		"answer":      data.Answer,
+		"answerer_id": user.ID,
+		"is_public":   data.IsPublic}).Error; err != nil {
+		global.LOG.Panic("AnswerClarification: answer clarification error")
+	}
+	service.PublishClarificationEvent(clarification)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "回答成功"})
+}
+
+// GetClarification
+// @Summary      获取比赛答疑
+// @Description  获取用户可见的比赛答疑，参赛者可见公开答疑与自己的提问，管理员可见所有答疑；获取后这些答疑被标记为已读
+// @Tags         比赛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                   true   "token"
+// @Param        id       path      int                      true   "比赛ID"
+// @Param        unread   query     bool                     false  "是否仅获取未读的答疑，用于轮询"
+// @Success      200      {object}  model.GetClarificationA  "是否成功，返回信息，当前用户是否为管理员，答疑列表"
+// @Router       /api/v1/contests/{id}/clarifications [get]
+func GetClarification(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	unread := c.Query("unread") == "true"
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetClarificationA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 比赛的存在性判定
+	contest, notFound := service.GetContestByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetClarificationA{Success: false, Message: "比赛不存在"})
+		return
+	}
+	// 用户权限判定
+	isAdmin := service.IsOrganizationAdmin(user.ID, contest.OrgID)
+	if !isAdmin && !service.JudgeContestReadPermission(user.ID, contest) {
+		c.JSON(http.StatusOK, model.GetClarificationA{Success: false, Message: "您对该比赛无可读权限"})
+		return
+	}
+	// 获取答疑，并更新已读时间
+	now := time.Now()
+	since := time.Time{}
+	if unread {
+		since = service.GetClarificationReadTime(user.ID, contest.ID)
+	}
+	clarifications := service.GetVisibleClarifications(user.ID, contest.ID, isAdmin, since)
+	service.UpdateClarificationReadTime(user.ID, contest.ID, now)
+	// 获取提问者名称
+	creatorIDs := make([]uint64, 0)
+	for _, clarification := range clarifications {
+		creatorIDs = append(creatorIDs, clarification.CreatorID)
+	}
+	creators := service.GetUsersByIDs(creatorIDs)
+	finalClarifications := make([]model.ClarificationT, 0)
+	for _, clarification := range clarifications {
+		finalClarifications = append(finalClarifications, model.ClarificationT{
+			ID:             clarification.ID,
+			ProblemID:      clarification.ProblemID,
+			CreatorID:      clarification.CreatorID,
+			CreatorName:    creators[clarification.CreatorID].Name,
+			Question:       clarification.Question,
+			Answer:         clarification.Answer,
+			IsPublic:       clarification.IsPublic,
+			IsAnnouncement: clarification.IsAnnouncement,
+			UpdatedAt:      clarification.UpdatedAt.Format("2006-01-02 15:04:05")})
+	}
+	// 返回响应
+	c.JSON(http.StatusOK, model.GetClarificationA{
+		Success:        true,
+		IsAdmin:        isAdmin,
+		Clarifications: finalClarifications})
+}
+
 // GetOrganizationProblem
 // @Summary      获取组织中的题目
 // @Description  获取组织中的管理员可见的题目，即属于组织管理员可读的题目
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/service"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"io"
+	"time"
+)
+
+// GetEventStream
+// @Summary      订阅推送事件
+// @Description  以Server-Sent Events的形式推送当前用户有权获取的事件，包括评测结果(verdict)、站内通知(notification)、私信(message)、比赛答疑(clarification)与榜单变化(scoreboard)；断线重连时通过Last-Event-ID续传，无法续传时推送reset事件，客户端应重新拉取数据
+// @Tags         推送模块
+// @Produce      text/event-stream
+// @Param        x-token        header    string  true   "token"
+// @Param        Last-Event-ID  header    string  false  "最后收到的事件ID"
+// @Param        lastEventID    query     string  false  "最后收到的事件ID，请求头中没有时使用"
+// @Success      200            {string}  string  "事件流"
+// @Router       /api/v1/events [get]
+func GetEventStream(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventID")
+	}
+	// 订阅事件
+	ch, backlog, complete := service.SubscribeEvents(lastEventID)
+	defer service.UnsubscribeEvents(ch)
+	filter := service.NewEventFilter(user.ID)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	// 推送断线期间的事件
+	if !complete {
+		c.Render(-1, sse.Event{Event: service.EventTypeReset, Data: ""})
+	}
+	for _, event := range backlog {
+		if filter.Allow(event) {
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event.Data})
+		}
+	}
+	c.Writer.Flush()
+	// 持续推送新事件，并定期发送心跳与刷新权限
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if filter.Allow(event) {
+				c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event.Data})
+			}
+			return true
+		case <-heartbeat.C:
+			filter.Refresh()
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/phoenix-next/phoenix-server/utils"
 	"net/http"
 	"strconv"
+	"time"
+	"unicode/utf8"
 )
 
 // CreatePost
@@ -25,27 +27,37 @@ func CreatePost(c *gin.Context) {
 	// 获取数据
 	data := utils.BindJsonData(c, &model.CreatePostQ{}).(*model.CreatePostQ)
 	user := utils.SolveUser(c)
+	if utf8.RuneCountInString(data.Content) > service.PostContentMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子内容过长"})
+		return
+	}
 	// 用户权限判定
 	if ok, err := service.IsUserInThisOrganization(user.ID, data.OrgID); !ok || err != nil {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有权限在此发帖"})
 		return
 	}
 	// 成功新建帖子
+	now := time.Now()
 	post := model.Post{
-		Content:   data.Content,
-		OrgID:     data.OrgID,
-		CreatorID: user.ID,
-		Type:      data.Type,
-		Title:     data.Title}
+		Content:          data.Content,
+		ContentHTML:      utils.RenderMarkdown(data.Content),
+		OrgID:            data.OrgID,
+		CreatorID:        user.ID,
+		Type:             data.Type,
+		Title:            data.Title,
+		LastActivityTime: &now}
 	if err := global.DB.Create(&post).Error; err != nil {
 		global.LOG.Panic("CreatePost: can create post")
 	}
+	service.IndexPost(post)
+	service.NotifyMentions(user.ID, post.OrgID, post.ID, 0, post.Content, nil)
+	service.NotifySubscribers(post, nil)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "发帖成功"})
 }
 
 // DeletePost
 // @Summary      删除帖子
-// @Description  删除一个帖子，删除者可以是帖子创建者或者组织管理员
+// @Description  删除一个帖子，删除者可以是帖子创建者或者组织管理员；删除的帖子在保留期内可由管理员恢复
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
@@ -64,13 +76,19 @@ func DeletePost(c *gin.Context) {
 	}
 	// 删帖权限判定
 	if post.CreatorID == user.ID {
-		global.DB.Delete(&post)
+		if err := service.DeletePost(post, user.ID); err != nil {
+			global.LOG.Panic("DeletePost: delete post error")
+		}
+		service.RemoveSearchDocument(service.SearchTypePost, post.ID)
 		c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删帖成功"})
 		return
 	}
 	for _, admin := range service.GetOrganizationAdmin(post.OrgID) {
 		if admin.UserID == user.ID {
-			global.DB.Delete(&post)
+			if err := service.DeletePost(post, user.ID); err != nil {
+				global.LOG.Panic("DeletePost: delete post error")
+			}
+			service.RemoveSearchDocument(service.SearchTypePost, post.ID)
 			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删帖成功"})
 			return
 		}
@@ -81,7 +99,7 @@ func DeletePost(c *gin.Context) {
 
 // UpdatePost
 // @Summary      更新帖子内容
-// @Description  更新一个帖子的内容，更新者可以是帖子创建者或者组织管理员
+// @Description  更新一个帖子的内容，更新者可以是帖子创建者或者组织管理员，标题或内容的每次修改都会记录修订版本
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
@@ -94,6 +112,10 @@ func UpdatePost(c *gin.Context) {
 	// 获取数据
 	user := utils.SolveUser(c)
 	data := utils.BindJsonData(c, &model.UpdatePostQ{}).(*model.UpdatePostQ)
+	if utf8.RuneCountInString(data.Content) > service.PostContentMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子内容过长"})
+		return
+	}
 	post, ok := service.GetPostFromParam(c)
 	// 帖子存在性判定
 	if !ok {
@@ -102,13 +124,19 @@ func UpdatePost(c *gin.Context) {
 	}
 	// 更新权限判定
 	if post.CreatorID == user.ID {
-		global.DB.Model(&post).Updates(model.Post{Content: data.Content, Title: data.Title, Type: data.Type})
+		if err := service.UpdatePost(&post, data.Title, data.Content, data.Type, user.ID); err != nil {
+			global.LOG.Panic("UpdatePost: update post error")
+		}
+		service.IndexPost(post)
 		c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新帖子成功"})
 		return
 	}
 	for _, admin := range service.GetOrganizationAdmin(post.OrgID) {
 		if admin.UserID == user.ID {
-			global.DB.Model(&post).Updates(model.Post{Content: data.Content, Title: data.Title, Type: data.Type})
+			if err := service.UpdatePost(&post, data.Title, data.Content, data.Type, user.ID); err != nil {
+				global.LOG.Panic("UpdatePost: update post error")
+			}
+			service.IndexPost(post)
 			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新帖子成功"})
 			return
 		}
@@ -119,13 +147,13 @@ func UpdatePost(c *gin.Context) {
 
 // GetPost
 // @Summary      获取帖子详细信息
-// @Description  获取一个帖子的详细信息，用户必须是该帖子所在组织的成员
+// @Description  获取一个帖子的详细信息，组织板块中的帖子要求用户是组织成员，题目讨论中的帖子要求用户可读题目，包含题解的帖子还要求用户已通过题目
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
 // @Param        x-token  header    string          true  "token"
 // @Param        id       path      int             true  "帖子ID"
-// @Success      200      {object}  model.GetPostA  "当前用户是否是帖子所在组织的管理员，创建者ID，创建者名字，创建者头像路径，标题，内容，最后更新时间"
+// @Success      200      {object}  model.GetPostA  "当前用户是否是帖子所在组织的管理员，创建者ID，创建者名字，创建者头像路径，标题，内容，评论总数，点赞数，采纳的答案，表情回应，最后更新时间"
 // @Router       /api/v1/posts/{id} [get]
 func GetPost(c *gin.Context) {
 	// 帖子存在性判定
@@ -136,7 +164,7 @@ func GetPost(c *gin.Context) {
 	}
 	// 用户权限判定
 	user := utils.SolveUser(c)
-	if ok, _ = service.IsUserInThisOrganization(user.ID, post.OrgID); !ok {
+	if !service.JudgePostReadPermission(post, c) {
 		c.JSON(http.StatusOK, model.GetPostA{Success: false, Message: "用户没有查阅权限"})
 		return
 	}
@@ -150,14 +178,25 @@ func GetPost(c *gin.Context) {
 	}
 	// 返回响应
 	c.JSON(http.StatusOK, model.GetPostA{
-		Success:       true,
-		CreatorID:     post.CreatorID,
-		CreatorName:   creator.Name,
-		CreatorAvatar: creator.Avatar,
-		Title:         post.Title,
-		Content:       post.Content,
-		UpdatedAt:     post.UpdatedAt.Format("01-02 15:04"),
-		IsAdmin:       isAdmin})
+		Success:           true,
+		CreatorID:         post.CreatorID,
+		CreatorName:       creator.Name,
+		CreatorAvatar:     creator.Avatar,
+		Title:             post.Title,
+		Content:           post.Content,
+		ContentHTML:       service.GetContentHTML(post.Content, post.ContentHTML),
+		CommentCount:      post.CommentCount,
+		Score:             post.Score,
+		AcceptedCommentID: post.AcceptedCommentID,
+		Reactions:         service.GetReactionSummaries(service.ForumTargetPost, []uint64{post.ID}, user.ID)[post.ID],
+		IsPinned:          post.IsPinned,
+		IsLocked:          post.IsLocked,
+		EditCount:         post.EditCount,
+		EditedAt:          service.FormatEditedAt(post.EditedAt),
+		ProblemID:         post.ProblemID,
+		IsSolution:        post.IsSolution,
+		UpdatedAt:         post.UpdatedAt.Format("01-02 15:04"),
+		IsAdmin:           isAdmin})
 }
 
 // GetAllPost
@@ -171,6 +210,7 @@ func GetPost(c *gin.Context) {
 // @Param        type     query     int                true   "帖子板块"
 // @Param        page     query     int                true   "位于第几页，页数从1开始"
 // @Param        keyWord  query     string             false  "帖子标题查找关键字，模糊匹配"
+// @Param        sorter   query     int                false  "排序方式，0为按更新时间，1为按点赞数，2为按最近活跃时间，3为仅看未采纳答案的帖子"
 // @Success      200      {object}  model.GetAllPostA  "是否成功，返回信息，帖子总数，帖子列表"
 // @Router       /api/v1/posts [get]
 func GetAllPost(c *gin.Context) {
@@ -179,10 +219,15 @@ func GetAllPost(c *gin.Context) {
 	postType, err2 := strconv.Atoi(c.Query("type"))
 	page, err3 := strconv.Atoi(c.Query("page"))
 	keyWord := c.Query("keyWord")
+	sorter, err4 := strconv.Atoi(c.DefaultQuery("sorter", "0"))
 	if err1 != nil || err2 != nil || err3 != nil {
 		c.JSON(http.StatusOK, model.GetAllPostA{Success: false, Message: "参数非法"})
 		return
 	}
+	if err4 != nil || !service.IsValidPostSorter(sorter) {
+		c.JSON(http.StatusOK, model.GetAllPostA{Success: false, Message: "请求参数非法"})
+		return
+	}
 	// 判断用户权限
 	user := utils.SolveUser(c)
 	if ok, err := service.IsUserInThisOrganization(user.ID, oid); !ok || err != nil {
@@ -190,7 +235,7 @@ func GetAllPost(c *gin.Context) {
 		return
 	}
 	// 得到所有帖子，并进行模糊查找
-	rawPosts := service.GetAllPosts(oid, postType)
+	rawPosts := service.GetAllPosts(oid, postType, sorter)
 	posts := make([]model.Post, 0)
 	for _, item := range rawPosts {
 		if fuzzy.MatchFold(keyWord, item.Title) {
@@ -206,35 +251,23 @@ func GetAllPost(c *gin.Context) {
 		return
 	}
 	// 获取帖子总页数
-	totalPage := (len(posts)-1)/5 + 1
+	totalPage := (len(posts)-1)/service.PostPageSize + 1
 	// 页数不合法的情况
 	if page <= 0 || page > totalPage {
 		c.JSON(http.StatusOK, model.GetAllPostA{Success: false, Message: "页数非法"})
 		return
 	}
 	// 获取端点位置，并对帖子切片
-	start, end := (page-1)*5, page*5
+	start, end := (page-1)*service.PostPageSize, page*service.PostPageSize
 	if length := len(posts); end > length {
 		end = length
 	}
 	filteredPosts := posts[start:end]
-	// 获取帖子创建者的名称和头像
-	finalPosts := make([]model.PostT, 0)
-	for _, item := range filteredPosts {
-		creator, _ := service.GetUserByID(item.CreatorID)
-		finalPosts = append(finalPosts, model.PostT{
-			ID:            item.ID,
-			CreatorID:     item.CreatorID,
-			UpdatedAt:     item.UpdatedAt.Format("01-02 15:04"),
-			Title:         item.Title,
-			CreatorAvatar: creator.Avatar,
-			CreatorName:   creator.Name})
-	}
 	// 返回响应
 	c.JSON(http.StatusOK, model.GetAllPostA{
 		Success: true,
 		Total:   len(posts),
-		Posts:   finalPosts})
+		Posts:   service.BuildPosts(filteredPosts)})
 }
 
 // CreateComment
@@ -257,24 +290,63 @@ func CreateComment(c *gin.Context) {
 	}
 	// 用户权限判定
 	user := utils.SolveUser(c)
-	if ok, err := service.IsUserInThisOrganization(user.ID, post.OrgID); !ok || err != nil {
+	if !service.JudgePostReadPermission(post, c) {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有权限进行该操作"})
 		return
 	}
-	// 成功创建评论
+	// 帖子锁定后仅管理员可以评论
+	if post.IsLocked && !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子已锁定，无法评论"})
+		return
+	}
+	// 被回复评论的存在性判定
 	data := utils.BindJsonData(c, &model.CreateCommentQ{}).(*model.CreateCommentQ)
-	global.DB.Create(&model.Comment{
-		OrgID:     post.OrgID,
-		Content:   data.Content,
-		ToID:      data.ToID,
-		PostID:    post.ID,
-		CreatorID: user.ID})
+	if utf8.RuneCountInString(data.Content) > service.CommentContentMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论内容过长"})
+		return
+	}
+	var parent *model.Comment
+	if data.ToID != 0 {
+		comment, notFound := service.GetCommentByID(data.ToID)
+		if notFound || comment.PostID != post.ID {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "回复的评论不存在"})
+			return
+		}
+		parent = &comment
+	}
+	// 成功创建评论
+	comment := model.Comment{
+		OrgID:       post.OrgID,
+		Content:     data.Content,
+		ContentHTML: utils.RenderMarkdown(data.Content),
+		PostID:      post.ID,
+		CreatorID:   user.ID}
+	if err := service.CreateComment(&comment, parent); err != nil {
+		global.LOG.Panic("CreateComment: create comment error")
+	}
+	// 通知被回复者与被提及者
+	receiverID := post.CreatorID
+	if parent != nil {
+		receiverID = parent.CreatorID
+	}
+	service.Notify(model.Notification{
+		UserID:   receiverID,
+		Type:     service.NotificationTypeReply,
+		ActorID:  user.ID,
+		TargetID: post.ID,
+		SourceID: comment.ID,
+		Content:  comment.Content})
+	// 通知中的摘要可能泄露题解，因此题解帖子中的提及不发送通知
+	if !post.IsSolution {
+		service.NotifyMentions(user.ID, post.OrgID, post.ID, comment.ID, comment.Content, map[uint64]bool{receiverID: true})
+	}
+	service.NotifySubscribers(post, &comment)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "评论成功"})
 }
 
 // UpdateComment
 // @Summary      更新评论
-// @Description  更新一条评论的内容，用户是评论创建者或者组织管理员
+// @Description  更新一条评论的内容，用户是评论创建者或者组织管理员，内容的每次修改都会记录修订版本
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
@@ -293,14 +365,22 @@ func UpdateComment(c *gin.Context) {
 	// 用户权限判定
 	user := utils.SolveUser(c)
 	data := utils.BindJsonData(c, &model.UpdateCommentQ{}).(*model.UpdateCommentQ)
+	if utf8.RuneCountInString(data.Content) > service.CommentContentMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论内容过长"})
+		return
+	}
 	if user.ID == comment.CreatorID {
-		global.DB.Model(&comment).Updates(model.Comment{Content: data.Content})
+		if err := service.UpdateComment(comment, data.Content, user.ID); err != nil {
+			global.LOG.Panic("UpdateComment: update comment error")
+		}
 		c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "评论更新成功"})
 		return
 	}
 	for _, admin := range service.GetOrganizationAdmin(comment.OrgID) {
 		if admin.UserID == user.ID {
-			global.DB.Model(&comment).Updates(model.Comment{Content: data.Content})
+			if err := service.UpdateComment(comment, data.Content, user.ID); err != nil {
+				global.LOG.Panic("UpdateComment: update comment error")
+			}
 			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "评论更新成功"})
 			return
 		}
@@ -311,7 +391,7 @@ func UpdateComment(c *gin.Context) {
 
 // DeleteComment
 // @Summary      删除评论
-// @Description  删除一条评论，删者是评论创建者或者组织管理员
+// @Description  删除一条评论及其所有回复，删者是评论创建者或者组织管理员；删除的评论在保留期内可由管理员恢复
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
@@ -330,13 +410,17 @@ func DeleteComment(c *gin.Context) {
 	}
 	// 删除评论权限判定
 	if comment.CreatorID == user.ID {
-		global.DB.Delete(&comment)
+		if err := service.DeleteComment(comment, user.ID); err != nil {
+			global.LOG.Panic("DeleteComment: delete comment error")
+		}
 		c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除评论成功"})
 		return
 	}
 	for _, admin := range service.GetOrganizationAdmin(comment.OrgID) {
 		if admin.UserID == user.ID {
-			global.DB.Delete(&comment)
+			if err := service.DeleteComment(comment, user.ID); err != nil {
+				global.LOG.Panic("DeleteComment: delete comment error")
+			}
 			c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除评论成功"})
 			return
 		}
@@ -347,13 +431,14 @@ func DeleteComment(c *gin.Context) {
 
 // GetComment
 // @Summary      获取评论
-// @Description  获取一个帖子下的所有评论，用户必须属于该帖子所在的组织
+// @Description  分页获取一个帖子下的顶层评论，每条顶层评论附带若干层回复，更深的回复需通过获取回复接口加载，用户必须属于该帖子所在的组织
 // @Tags         论坛模块
 // @Accept       json
 // @Produce      json
-// @Param        x-token  header    string             true  "token"
-// @Param        id       path      int                true  "帖子ID"
-// @Success      200      {object}  model.GetCommentA  "是否成功，返回信息，评论列表"
+// @Param        x-token  header    string             true   "token"
+// @Param        id       path      int                true   "帖子ID"
+// @Param        page     query     int                false  "位于第几页，页数从1开始，默认为1"
+// @Success      200      {object}  model.GetCommentA  "是否成功，返回信息，顶层评论总数，评论总数，评论树列表"
 // @Router       /api/v1/posts/{id}/comments [get]
 func GetComment(c *gin.Context) {
 	// 帖子存在性判定
@@ -364,22 +449,799 @@ func GetComment(c *gin.Context) {
 	}
 	// 用户权限判定
 	user := utils.SolveUser(c)
-	if ok, err := service.IsUserInThisOrganization(user.ID, post.OrgID); !ok || err != nil {
+	if !service.JudgePostReadPermission(post, c) {
 		c.JSON(http.StatusOK, model.GetCommentA{Success: false, Message: "用户没有权限获取该帖子评论"})
 		return
 	}
-	// 成功获取评论
-	comments := make([]model.CommentT, 0)
-	for _, comment := range service.GetAllCommentByPostID(post.ID) {
-		tmp, _ := service.GetUserByID(comment.CreatorID)
-		comments = append(comments, model.CommentT{
-			ID:            comment.ID,
-			CreatorID:     comment.CreatorID,
-			UpdatedAt:     comment.UpdatedAt.Format("01-02 15:04"),
-			CreatorName:   tmp.Name,
-			ToID:          comment.CreatorID,
-			Content:       comment.Content,
-			CreatorAvatar: tmp.Avatar})
-	}
-	c.JSON(http.StatusOK, model.GetCommentA{Success: true, Comments: comments})
+	// 页数合法性判定
+	page := 1
+	if c.Query("page") != "" {
+		var err error
+		if page, err = strconv.Atoi(c.Query("page")); err != nil || page <= 0 {
+			c.JSON(http.StatusOK, model.GetCommentA{Success: false, Message: "页数非法"})
+			return
+		}
+	}
+	// 获取当前页的顶层评论及其回复
+	roots, total := service.GetTopCommentsByPage(post.ID, page)
+	rootIDs := make([]uint64, 0)
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+	comments := append(roots, service.GetRepliesByRootIDs(rootIDs, 0, service.CommentInlineDepth)...)
+	c.JSON(http.StatusOK, model.GetCommentA{
+		Success:      true,
+		Total:        total,
+		CommentCount: post.CommentCount,
+		Comments:     service.BuildCommentTree(post, comments, 0, service.CommentInlineDepth, user.ID)})
+}
+
+// GetCommentReply
+// @Summary      获取评论的回复
+// @Description  获取一条评论下若干层的回复，用于加载获取评论接口中未展开的深层回复，用户必须属于该评论所在的组织
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       path      int                     true  "评论ID"
+// @Success      200      {object}  model.GetCommentReplyA  "是否成功，返回信息，回复树列表"
+// @Router       /api/v1/comments/{id}/replies [get]
+func GetCommentReply(c *gin.Context) {
+	// 评论存在性判定
+	comment, ok := service.GetCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetCommentReplyA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	post, _ := service.GetPostByID(comment.PostID)
+	if !service.JudgePostReadPermission(post, c) {
+		c.JSON(http.StatusOK, model.GetCommentReplyA{Success: false, Message: "用户没有权限获取该评论的回复"})
+		return
+	}
+	// 获取同一评论树中更深的回复，并仅保留该评论的子树
+	rootID := comment.RootID
+	if rootID == 0 {
+		rootID = comment.ID
+	}
+	maxDepth := comment.Depth + service.CommentInlineDepth
+	replies := service.GetRepliesByRootIDs([]uint64{rootID}, comment.Depth, maxDepth)
+	c.JSON(http.StatusOK, model.GetCommentReplyA{
+		Success: true,
+		Replies: service.BuildCommentTree(post, replies, comment.ID, maxDepth, user.ID)})
+}
+
+// ReactPost
+// @Summary      回应帖子
+// @Description  对帖子做出或撤销表情回应，每个用户对同一帖子的同一表情至多回应一次，点赞的表情为+1，用户必须属于该帖子所在的组织
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string        true  "token"
+// @Param        id       path      int           true  "帖子ID"
+// @Param        data     body      model.ReactQ  true  "表情名称"
+// @Success      200      {object}  model.ReactA  "是否成功，返回信息，当前用户是否做出了该回应，帖子的表情回应"
+// @Router       /api/v1/posts/{id}/reactions [post]
+func ReactPost(c *gin.Context) {
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.JudgePostReadPermission(post, c) {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "用户没有权限回应该帖子"})
+		return
+	}
+	// 表情合法性判定
+	data := utils.BindJsonData(c, &model.ReactQ{}).(*model.ReactQ)
+	if !service.IsAllowedEmoji(data.Emoji) {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "不支持该表情"})
+		return
+	}
+	// 切换回应
+	isReacted, err := service.ToggleReaction(user.ID, service.ForumTargetPost, post.ID, data.Emoji)
+	if err != nil {
+		global.LOG.Panic("ReactPost: toggle reaction error")
+	}
+	c.JSON(http.StatusOK, model.ReactA{
+		Success:   true,
+		Message:   "回应成功",
+		IsReacted: isReacted,
+		Reactions: service.GetReactionSummaries(service.ForumTargetPost, []uint64{post.ID}, user.ID)[post.ID]})
+}
+
+// ReactComment
+// @Summary      回应评论
+// @Description  对评论做出或撤销表情回应，每个用户对同一评论的同一表情至多回应一次，点赞的表情为+1，用户必须属于该评论所在的组织
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string        true  "token"
+// @Param        id       path      int           true  "评论ID"
+// @Param        data     body      model.ReactQ  true  "表情名称"
+// @Success      200      {object}  model.ReactA  "是否成功，返回信息，当前用户是否做出了该回应，评论的表情回应"
+// @Router       /api/v1/comments/{id}/reactions [post]
+func ReactComment(c *gin.Context) {
+	// 评论存在性判定
+	comment, ok := service.GetCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if post, _ := service.GetPostByID(comment.PostID); !service.JudgePostReadPermission(post, c) {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "用户没有权限回应该评论"})
+		return
+	}
+	// 表情合法性判定
+	data := utils.BindJsonData(c, &model.ReactQ{}).(*model.ReactQ)
+	if !service.IsAllowedEmoji(data.Emoji) {
+		c.JSON(http.StatusOK, model.ReactA{Success: false, Message: "不支持该表情"})
+		return
+	}
+	// 切换回应
+	isReacted, err := service.ToggleReaction(user.ID, service.ForumTargetComment, comment.ID, data.Emoji)
+	if err != nil {
+		global.LOG.Panic("ReactComment: toggle reaction error")
+	}
+	c.JSON(http.StatusOK, model.ReactA{
+		Success:   true,
+		Message:   "回应成功",
+		IsReacted: isReacted,
+		Reactions: service.GetReactionSummaries(service.ForumTargetComment, []uint64{comment.ID}, user.ID)[comment.ID]})
+}
+
+// AcceptAnswer
+// @Summary      采纳答案
+// @Description  将讨论板块帖子下的一条评论采纳为答案，每个帖子至多采纳一条，操作者必须是帖子创建者或者组织管理员
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "帖子ID"
+// @Param        data     body      model.AcceptAnswerQ  true  "被采纳的评论ID，为0表示取消采纳"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/posts/{id}/accepted-answer [put]
+func AcceptAnswer(c *gin.Context) {
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	if post.Type != service.PostTypeDiscussion {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "只有讨论板块的帖子可以采纳答案"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if post.CreatorID != user.ID && !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "没有权限采纳答案"})
+		return
+	}
+	// 评论存在性判定
+	data := utils.BindJsonData(c, &model.AcceptAnswerQ{}).(*model.AcceptAnswerQ)
+	if data.CommentID != 0 {
+		if comment, notFound := service.GetCommentByID(data.CommentID); notFound || comment.PostID != post.ID {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论不存在"})
+			return
+		}
+	}
+	// 采纳答案
+	if err := service.AcceptAnswer(post, data.CommentID); err != nil {
+		global.LOG.Panic("AcceptAnswer: accept answer error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "操作成功"})
+}
+
+// ModeratePost
+// @Summary      管理帖子
+// @Description  组织管理员置顶、锁定帖子，将帖子移动到其他板块，或标记题目讨论中的帖子是否包含题解，置顶的帖子排在板块最前，锁定的帖子仅管理员可以评论
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "帖子ID"
+// @Param        data     body      model.ModeratePostQ  true  "是否置顶，是否锁定，是否包含题解，移动到的板块，为空的字段不修改"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/posts/{id}/moderation [put]
+func ModeratePost(c *gin.Context) {
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 获取需要修改的字段
+	data := utils.BindJsonData(c, &model.ModeratePostQ{}).(*model.ModeratePostQ)
+	updates := make(map[string]interface{})
+	if data.IsPinned != nil {
+		updates["is_pinned"] = *data.IsPinned
+	}
+	if data.IsLocked != nil {
+		updates["is_locked"] = *data.IsLocked
+	}
+	if data.IsSolution != nil {
+		if post.ProblemID == 0 {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "只有题目讨论中的帖子可以标记题解"})
+			return
+		}
+		updates["is_solution"] = *data.IsSolution
+	}
+	if data.Type != nil {
+		if *data.Type < 0 || *data.Type > service.PostTypeDiscussion {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "板块不存在"})
+			return
+		}
+		updates["type"] = *data.Type
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "没有需要修改的内容"})
+		return
+	}
+	if err := service.ModeratePost(post, updates); err != nil {
+		global.LOG.Panic("ModeratePost: moderate post error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "操作成功"})
+}
+
+// ReportPost
+// @Summary      举报帖子
+// @Description  组织成员举报帖子，举报进入组织的审核队列，同一用户对同一帖子只能有一条待处理的举报
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "帖子ID"
+// @Param        data     body      model.CreateReportQ  true  "举报理由"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/posts/{id}/reports [post]
+func ReportPost(c *gin.Context) {
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	createReport(c, post, service.ForumTargetPost, post.ID)
+}
+
+// ReportComment
+// @Summary      举报评论
+// @Description  组织成员举报评论，举报进入组织的审核队列，同一用户对同一评论只能有一条待处理的举报
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "评论ID"
+// @Param        data     body      model.CreateReportQ  true  "举报理由"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/comments/{id}/reports [post]
+func ReportComment(c *gin.Context) {
+	// 评论存在性判定
+	comment, ok := service.GetCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论不存在"})
+		return
+	}
+	post, _ := service.GetPostByID(comment.PostID)
+	createReport(c, post, service.ForumTargetComment, comment.ID)
+}
+
+// createReport 举报帖子或评论的公共逻辑，post为被举报对象所在的帖子
+func createReport(c *gin.Context, post model.Post, targetType int, targetID uint64) {
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.JudgePostReadPermission(post, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有权限进行该操作"})
+		return
+	}
+	// 举报理由与重复举报判定
+	data := utils.BindJsonData(c, &model.CreateReportQ{}).(*model.CreateReportQ)
+	if data.Reason == "" {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "举报理由不能为空"})
+		return
+	}
+	if service.HasPendingReport(user.ID, targetType, targetID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "已经举报过，请等待管理员处理"})
+		return
+	}
+	// 创建举报
+	if err := global.DB.Create(&model.Report{
+		OrgID:      post.OrgID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReporterID: user.ID,
+		Reason:     data.Reason}).Error; err != nil {
+		global.LOG.Panic("createReport: create report error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "举报成功"})
+}
+
+// GetReport
+// @Summary      获取审核队列
+// @Description  组织管理员获取组织内对帖子与评论的举报，按时间降序排列
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string            true   "token"
+// @Param        id       query     int               true   "组织ID"
+// @Param        status   query     int               false  "举报状态，0为待处理，1为已处理，2为已驳回，为空表示不限"
+// @Success      200      {object}  model.GetReportA  "是否成功，返回信息，举报列表"
+// @Router       /api/v1/reports [get]
+func GetReport(c *gin.Context) {
+	// 获取请求数据
+	oid, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	status := -1
+	if c.Query("status") != "" {
+		status, err = strconv.Atoi(c.Query("status"))
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetReportA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, oid) {
+		c.JSON(http.StatusOK, model.GetReportA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetReportA{Success: true, Reports: service.BuildReports(service.GetReports(oid, status))})
+}
+
+// HandleReport
+// @Summary      处理举报
+// @Description  组织管理员将举报标记为已处理或已驳回，标记为已处理时可以同时删除被举报的对象
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "举报ID"
+// @Param        data     body      model.HandleReportQ  true  "处理结果，是否删除被举报的对象"
+// @Success      200      {object}  model.CommonA        "是否成功，返回信息"
+// @Router       /api/v1/reports/{id} [put]
+func HandleReport(c *gin.Context) {
+	// 举报存在性判定
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	report, notFound := service.GetReportByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "举报不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, report.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 处理结果合法性判定
+	data := utils.BindJsonData(c, &model.HandleReportQ{}).(*model.HandleReportQ)
+	if data.Status != service.ReportStatusResolved && data.Status != service.ReportStatusDismissed {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "处理结果非法"})
+		return
+	}
+	// 删除被举报的对象，对象已被删除时忽略
+	if data.Status == service.ReportStatusResolved && data.DeleteTarget {
+		if report.TargetType == service.ForumTargetPost {
+			if post, notFound := service.GetPostByID(report.TargetID); !notFound {
+				if err := service.DeletePost(post, user.ID); err != nil {
+					global.LOG.Panic("HandleReport: delete post error")
+				}
+				service.RemoveSearchDocument(service.SearchTypePost, post.ID)
+			}
+		} else if comment, notFound := service.GetCommentByID(report.TargetID); !notFound {
+			if err := service.DeleteComment(comment, user.ID); err != nil {
+				global.LOG.Panic("HandleReport: delete comment error")
+			}
+		}
+	}
+	if err := service.HandleReport(report, data.Status, user.ID); err != nil {
+		global.LOG.Panic("HandleReport: handle report error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "处理成功"})
+}
+
+// GetDeletedPost
+// @Summary      获取被删除的帖子
+// @Description  组织管理员获取组织内仍在保留期内的被删除帖子，按删除时间降序排列
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       query     int                     true  "组织ID"
+// @Success      200      {object}  model.GetDeletedForumA  "是否成功，返回信息，被删除的帖子列表"
+// @Router       /api/v1/deleted-posts [get]
+func GetDeletedPost(c *gin.Context) {
+	// 获取请求数据
+	oid, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetDeletedForumA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, oid) {
+		c.JSON(http.StatusOK, model.GetDeletedForumA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetDeletedForumA{Success: true, Items: service.BuildDeletedPosts(service.GetDeletedPosts(oid))})
+}
+
+// GetDeletedComment
+// @Summary      获取被删除的评论
+// @Description  组织管理员获取组织内仍在保留期内的被删除评论，随评论一并删除的回复不单独列出，按删除时间降序排列
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       query     int                     true  "组织ID"
+// @Success      200      {object}  model.GetDeletedForumA  "是否成功，返回信息，被删除的评论列表"
+// @Router       /api/v1/deleted-comments [get]
+func GetDeletedComment(c *gin.Context) {
+	// 获取请求数据
+	oid, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetDeletedForumA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, oid) {
+		c.JSON(http.StatusOK, model.GetDeletedForumA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetDeletedForumA{Success: true, Items: service.BuildDeletedComments(service.GetDeletedComments(oid))})
+}
+
+// RestorePost
+// @Summary      恢复帖子
+// @Description  组织管理员恢复保留期内被删除的帖子
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "帖子ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/posts/{id}/restore [post]
+func RestorePost(c *gin.Context) {
+	// 被删除帖子的存在性判定
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	post, notFound := service.GetDeletedPostByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "被删除的帖子不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 恢复帖子，并重建索引
+	if err := service.RestorePost(post); err != nil {
+		global.LOG.Panic("RestorePost: restore post error")
+	}
+	service.IndexPost(post)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "恢复帖子成功"})
+}
+
+// RestoreComment
+// @Summary      恢复评论
+// @Description  组织管理员恢复保留期内被删除的评论及随其一并删除的回复，评论所在的帖子与被回复的评论必须未被删除
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "评论ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/comments/{id}/restore [post]
+func RestoreComment(c *gin.Context) {
+	// 被删除评论的存在性判定
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	comment, notFound := service.GetDeletedCommentByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "被删除的评论不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if !service.IsOrganizationAdmin(user.ID, comment.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 帖子与被回复评论的存在性判定
+	if _, notFound = service.GetPostByID(comment.PostID); notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论所在的帖子已被删除，请先恢复帖子"})
+		return
+	}
+	if _, notFound = service.GetCommentByID(comment.ToID); comment.ToID != 0 && notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "被回复的评论已被删除，请先恢复该评论"})
+		return
+	}
+	if err := service.RestoreComment(comment); err != nil {
+		global.LOG.Panic("RestoreComment: restore comment error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "恢复评论成功"})
+}
+
+// GetPostRevision
+// @Summary      获取帖子的修订历史
+// @Description  获取帖子的所有修订版本，版本1为原始版本，用户必须是帖子创建者或者组织管理员
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string              true  "token"
+// @Param        id       path      int                 true  "帖子ID"
+// @Success      200      {object}  model.GetRevisionA  "是否成功，返回信息，修订版本列表"
+// @Router       /api/v1/posts/{id}/revisions [get]
+func GetPostRevision(c *gin.Context) {
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if post.CreatorID != user.ID && !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.GetRevisionA{Success: false, Message: "没有权限查看修订历史"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetRevisionA{
+		Success:   true,
+		Revisions: service.BuildRevisions(service.GetRevisions(service.ForumTargetPost, post.ID))})
+}
+
+// GetPostRevisionDiff
+// @Summary      比较帖子的修订版本
+// @Description  比较帖子的两个修订版本，标题作为第一行参与比较，用户必须是帖子创建者或者组织管理员
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       path      int                     true  "帖子ID"
+// @Param        from     query     int                     true  "旧版本号"
+// @Param        to       query     int                     true  "新版本号"
+// @Success      200      {object}  model.GetRevisionDiffA  "是否成功，返回信息，两个版本的差异"
+// @Router       /api/v1/posts/{id}/revisions/diff [get]
+func GetPostRevisionDiff(c *gin.Context) {
+	// 获取请求数据
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 帖子存在性判定
+	post, ok := service.GetPostFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "帖子不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if post.CreatorID != user.ID && !service.IsOrganizationAdmin(user.ID, post.OrgID) {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "没有权限查看修订历史"})
+		return
+	}
+	// 比较两个版本
+	diff, ok := service.DiffRevisions(service.GetRevisions(service.ForumTargetPost, post.ID), from, to)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "修订版本不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: true, Diff: diff})
+}
+
+// GetCommentRevision
+// @Summary      获取评论的修订历史
+// @Description  获取评论的所有修订版本，版本1为原始版本，用户必须是评论创建者或者组织管理员
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string              true  "token"
+// @Param        id       path      int                 true  "评论ID"
+// @Success      200      {object}  model.GetRevisionA  "是否成功，返回信息，修订版本列表"
+// @Router       /api/v1/comments/{id}/revisions [get]
+func GetCommentRevision(c *gin.Context) {
+	// 评论存在性判定
+	comment, ok := service.GetCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if comment.CreatorID != user.ID && !service.IsOrganizationAdmin(user.ID, comment.OrgID) {
+		c.JSON(http.StatusOK, model.GetRevisionA{Success: false, Message: "没有权限查看修订历史"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetRevisionA{
+		Success:   true,
+		Revisions: service.BuildRevisions(service.GetRevisions(service.ForumTargetComment, comment.ID))})
+}
+
+// GetCommentRevisionDiff
+// @Summary      比较评论的修订版本
+// @Description  比较评论的两个修订版本，用户必须是评论创建者或者组织管理员
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       path      int                     true  "评论ID"
+// @Param        from     query     int                     true  "旧版本号"
+// @Param        to       query     int                     true  "新版本号"
+// @Success      200      {object}  model.GetRevisionDiffA  "是否成功，返回信息，两个版本的差异"
+// @Router       /api/v1/comments/{id}/revisions/diff [get]
+func GetCommentRevisionDiff(c *gin.Context) {
+	// 获取请求数据
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 评论存在性判定
+	comment, ok := service.GetCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	user := utils.SolveUser(c)
+	if comment.CreatorID != user.ID && !service.IsOrganizationAdmin(user.ID, comment.OrgID) {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "没有权限查看修订历史"})
+		return
+	}
+	// 比较两个版本
+	diff, ok := service.DiffRevisions(service.GetRevisions(service.ForumTargetComment, comment.ID), from, to)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: false, Message: "修订版本不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, model.GetRevisionDiffA{Success: true, Diff: diff})
+}
+
+// GetProblemPost
+// @Summary      获取题目讨论
+// @Description  获取题目讨论中的帖子，用户必须可读该题目；包含题解的帖子仅对通过题目的用户、题目创建者与组织管理员可见；题目位于正在进行的比赛中时讨论关闭
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                 true   "token"
+// @Param        id       path      int                    true   "题目ID"
+// @Param        page     query     int                    true   "位于第几页，页数从1开始"
+// @Param        sorter   query     int                    false  "排序方式，0为按更新时间，1为按点赞数，2为按最近活跃时间，3为仅看未采纳答案的帖子"
+// @Success      200      {object}  model.GetProblemPostA  "是否成功，返回信息，帖子总数，被隐藏的题解数，讨论是否关闭，帖子列表"
+// @Router       /api/v1/problems/{id}/posts [get]
+func GetProblemPost(c *gin.Context) {
+	// 获取请求参数
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	page, err2 := strconv.Atoi(c.Query("page"))
+	sorter, err3 := strconv.Atoi(c.DefaultQuery("sorter", "0"))
+	if err1 != nil || err2 != nil || err3 != nil || page <= 0 || !service.IsValidPostSorter(sorter) {
+		c.JSON(http.StatusOK, model.GetProblemPostA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 题目存在性与用户权限判定
+	problem, notFound := service.GetProblemByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetProblemPostA{Success: false, Message: "题目不存在"})
+		return
+	}
+	if !service.JudgeProblemReadPermission(problem, c) {
+		c.JSON(http.StatusOK, model.GetProblemPostA{Success: false, Message: "您对该题目无可读权限"})
+		return
+	}
+	user := utils.SolveUser(c)
+	if service.IsDiscussionClosed(problem, user.ID) {
+		c.JSON(http.StatusOK, model.GetProblemPostA{Success: true, IsClosed: true, Posts: make([]model.PostT, 0)})
+		return
+	}
+	// 隐藏用户无法查看的题解
+	canViewSolution := service.CanViewSolution(problem, user.ID)
+	posts, hiddenCount := make([]model.Post, 0), 0
+	for _, post := range service.GetProblemPosts(problem.ID, sorter) {
+		if post.IsSolution && !canViewSolution && post.CreatorID != user.ID {
+			hiddenCount++
+			continue
+		}
+		posts = append(posts, post)
+	}
+	// 对帖子分页
+	start, end := (page-1)*service.PostPageSize, page*service.PostPageSize
+	if start > len(posts) {
+		start = len(posts)
+	}
+	if end > len(posts) {
+		end = len(posts)
+	}
+	c.JSON(http.StatusOK, model.GetProblemPostA{
+		Success:     true,
+		Total:       len(posts),
+		HiddenCount: hiddenCount,
+		Posts:       service.BuildPosts(posts[start:end])})
+}
+
+// CreateProblemPost
+// @Summary      在题目讨论中发帖
+// @Description  在题目讨论中新建帖子，用户必须可读该题目，题目位于正在进行的比赛中时无法发帖；包含题解的帖子仅对通过题目的用户可见
+// @Tags         论坛模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                    true  "token"
+// @Param        id       path      int                       true  "题目ID"
+// @Param        data     body      model.CreateProblemPostQ  true  "帖子标题，帖子内容，是否包含题解"
+// @Success      200      {object}  model.CommonA             "是否成功，返回信息"
+// @Router       /api/v1/problems/{id}/posts [post]
+func CreateProblemPost(c *gin.Context) {
+	// 获取请求参数
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	data := utils.BindJsonData(c, &model.CreateProblemPostQ{}).(*model.CreateProblemPostQ)
+	if utf8.RuneCountInString(data.Content) > service.PostContentMaxLength {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "帖子内容过长"})
+		return
+	}
+	// 题目存在性与用户权限判定
+	problem, notFound := service.GetProblemByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目不存在"})
+		return
+	}
+	if !service.JudgeProblemReadPermission(problem, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您对该题目无可读权限"})
+		return
+	}
+	user := utils.SolveUser(c)
+	if service.IsDiscussionClosed(problem, user.ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "题目位于正在进行的比赛中，讨论已关闭"})
+		return
+	}
+	// 成功新建帖子
+	now := time.Now()
+	post := model.Post{
+		Content:          data.Content,
+		ContentHTML:      utils.RenderMarkdown(data.Content),
+		OrgID:            problem.OrgID,
+		CreatorID:        user.ID,
+		Type:             service.PostTypeDiscussion,
+		ProblemID:        problem.ID,
+		IsSolution:       data.IsSolution,
+		Title:            data.Title,
+		LastActivityTime: &now}
+	if err := global.DB.Create(&post).Error; err != nil {
+		global.LOG.Panic("CreateProblemPost: create post error")
+	}
+	service.IndexPost(post)
+	if !post.IsSolution {
+		service.NotifyMentions(user.ID, post.OrgID, post.ID, 0, post.Content, nil)
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "发帖成功"})
 }
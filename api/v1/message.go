@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/service"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+// GetConversation
+// @Summary      获取会话列表
+// @Description  获取当前用户参与的所有单聊与群聊会话，按最近一条消息的时间降序排列
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Success      200      {object}  model.GetConversationA  "是否成功，返回信息，会话列表"
+// @Router       /api/v1/conversations [get]
+func GetConversation(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetConversationA{
+		Success:       true,
+		Conversations: service.BuildConversations(user.ID, service.GetConversations(user.ID))})
+}
+
+// CreateConversation
+// @Summary      创建会话
+// @Description  与一名用户单聊或与多名用户群聊，所有成员必须与当前用户同属至少一个组织，且与当前用户之间没有屏蔽关系；单聊会话已存在时返回原有的会话，群聊至多10人，群聊名称至多32个字符
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                     true  "token"
+// @Param        data     body      model.CreateConversationQ  true  "其他成员的ID，群聊名称"
+// @Success      200      {object}  model.CreateConversationA  "是否成功，返回信息，会话ID"
+// @Router       /api/v1/conversations [post]
+func CreateConversation(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CreateConversationQ{}).(*model.CreateConversationQ)
+	// 成员数量判定
+	memberIDs, visited := make([]uint64, 0), map[uint64]bool{user.ID: true}
+	for _, id := range data.UserIDs {
+		if !visited[id] {
+			visited[id] = true
+			memberIDs = append(memberIDs, id)
+		}
+	}
+	if len(memberIDs) == 0 || len(memberIDs)+1 > service.ConversationMaxMembers {
+		c.JSON(http.StatusOK, model.CreateConversationA{Success: false, Message: "会话成员数量非法"})
+		return
+	}
+	// 成员权限判定
+	for _, id := range memberIDs {
+		if _, notFound := service.GetUserByID(id); notFound {
+			c.JSON(http.StatusOK, model.CreateConversationA{Success: false, Message: "用户不存在"})
+			return
+		}
+		if !service.ShareOrganization(user.ID, id) {
+			c.JSON(http.StatusOK, model.CreateConversationA{Success: false, Message: "只能与同一组织的成员私信"})
+			return
+		}
+		if service.IsBlocked(user.ID, id) {
+			c.JSON(http.StatusOK, model.CreateConversationA{Success: false, Message: "与该用户之间存在屏蔽关系"})
+			return
+		}
+	}
+	// 单聊会话已存在时直接返回
+	conversation := model.Conversation{CreatorID: user.ID, IsGroup: len(memberIDs) > 1}
+	if conversation.IsGroup {
+		if utf8.RuneCountInString(data.Title) > service.ConversationTitleMax {
+			c.JSON(http.StatusOK, model.CreateConversationA{Success: false, Message: "群聊名称过长"})
+			return
+		}
+		conversation.Title = data.Title
+	} else {
+		if existing, notFound := service.GetPrivateConversation(user.ID, memberIDs[0]); !notFound {
+			c.JSON(http.StatusOK, model.CreateConversationA{Success: true, Message: "会话已存在", ID: existing.ID})
+			return
+		}
+		pairKey := service.GetPairKey(user.ID, memberIDs[0])
+		conversation.PairKey = &pairKey
+	}
+	if err := service.CreateConversation(&conversation, memberIDs); err != nil {
+		global.LOG.Panic("CreateConversation: create conversation error")
+	}
+	c.JSON(http.StatusOK, model.CreateConversationA{Success: true, Message: "创建会话成功", ID: conversation.ID})
+}
+
+// GetMessage
+// @Summary      获取消息记录
+// @Description  分页获取会话中的消息，按时间降序排列，不包括当前用户屏蔽的用户发送的消息，用户必须是会话成员
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string             true  "token"
+// @Param        id       path      int                true  "会话ID"
+// @Param        page     query     int                true  "位于第几页，页数从1开始"
+// @Success      200      {object}  model.GetMessageA  "是否成功，返回信息，消息总数，消息列表"
+// @Router       /api/v1/conversations/{id}/messages [get]
+func GetMessage(c *gin.Context) {
+	// 获取请求数据
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		c.JSON(http.StatusOK, model.GetMessageA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 会话存在性与用户权限判定
+	user := utils.SolveUser(c)
+	conversation, ok := service.GetConversationFromParam(c, user.ID)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetMessageA{Success: false, Message: "会话不存在"})
+		return
+	}
+	messages, total := service.GetMessagesByPage(conversation.ID, user.ID, page)
+	c.JSON(http.StatusOK, model.GetMessageA{Success: true, Total: total, Messages: service.BuildMessages(messages)})
+}
+
+// SendMessage
+// @Summary      发送消息
+// @Description  在会话中发送消息，新消息通过推送通道实时发送给其他成员；单聊双方之间存在屏蔽关系或不再同属任何组织时无法发送
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string              true  "token"
+// @Param        id       path      int                 true  "会话ID"
+// @Param        data     body      model.SendMessageQ  true  "消息内容"
+// @Success      200      {object}  model.SendMessageA  "是否成功，返回信息，发送的消息"
+// @Router       /api/v1/conversations/{id}/messages [post]
+func SendMessage(c *gin.Context) {
+	// 会话存在性与用户权限判定
+	user := utils.SolveUser(c)
+	conversation, ok := service.GetConversationFromParam(c, user.ID)
+	if !ok {
+		c.JSON(http.StatusOK, model.SendMessageA{Success: false, Message: "会话不存在"})
+		return
+	}
+	if !conversation.IsGroup {
+		for _, id := range service.GetConversationMemberIDs(conversation.ID) {
+			if id == user.ID {
+				continue
+			}
+			if service.IsBlocked(user.ID, id) {
+				c.JSON(http.StatusOK, model.SendMessageA{Success: false, Message: "与该用户之间存在屏蔽关系"})
+				return
+			}
+			if !service.ShareOrganization(user.ID, id) {
+				c.JSON(http.StatusOK, model.SendMessageA{Success: false, Message: "只能与同一组织的成员私信"})
+				return
+			}
+		}
+	}
+	// 消息内容合法性判定
+	data := utils.BindJsonData(c, &model.SendMessageQ{}).(*model.SendMessageQ)
+	if data.Content == "" || utf8.RuneCountInString(data.Content) > service.MessageMaxLength {
+		c.JSON(http.StatusOK, model.SendMessageA{Success: false, Message: "消息内容为空或过长"})
+		return
+	}
+	// 发送消息
+	message := model.Message{ConversationID: conversation.ID, SenderID: user.ID, Content: data.Content}
+	if err := service.SendMessage(conversation, &message); err != nil {
+		global.LOG.Panic("SendMessage: send message error")
+	}
+	c.JSON(http.StatusOK, model.SendMessageA{
+		Success:     true,
+		Message:     "发送成功",
+		SentMessage: service.BuildMessages([]model.Message{message})[0]})
+}
+
+// ReadConversation
+// @Summary      标记会话为已读
+// @Description  将会话中的消息全部标记为已读，用户必须是会话成员
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "会话ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/conversations/{id}/read [put]
+func ReadConversation(c *gin.Context) {
+	user := utils.SolveUser(c)
+	conversation, ok := service.GetConversationFromParam(c, user.ID)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "会话不存在"})
+		return
+	}
+	if err := service.ReadConversation(conversation, user.ID); err != nil {
+		global.LOG.Panic("ReadConversation: read conversation error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "标记成功"})
+}
+
+// LeaveConversation
+// @Summary      退出群聊
+// @Description  当前用户退出群聊，不再接收群聊中的消息；与群聊中的成员存在屏蔽关系时同样可以退出，单聊无法退出
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "会话ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/conversations/{id}/members/me [delete]
+func LeaveConversation(c *gin.Context) {
+	user := utils.SolveUser(c)
+	conversation, ok := service.GetConversationFromParam(c, user.ID)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "会话不存在"})
+		return
+	}
+	if !conversation.IsGroup {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "单聊无法退出"})
+		return
+	}
+	if err := service.LeaveConversation(conversation, user.ID); err != nil {
+		global.LOG.Panic("LeaveConversation: leave conversation error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "退出群聊成功"})
+}
+
+// GetUnreadMessageCount
+// @Summary      获取未读消息数
+// @Description  获取当前用户在所有会话中的未读消息总数，不包括当前用户屏蔽的用户发送的消息
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                        true  "token"
+// @Success      200      {object}  model.GetUnreadMessageCountA  "是否成功，返回信息，未读消息数"
+// @Router       /api/v1/conversations/unread-count [get]
+func GetUnreadMessageCount(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetUnreadMessageCountA{Success: true, Count: service.CountUnreadMessages(user.ID)})
+}
+
+// GetBlock
+// @Summary      获取屏蔽列表
+// @Description  获取当前用户屏蔽的所有用户
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string           true  "token"
+// @Success      200      {object}  model.GetBlockA  "是否成功，返回信息，屏蔽列表"
+// @Router       /api/v1/users/blocks [get]
+func GetBlock(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetBlockA{Success: true, Blocks: service.BuildBlocks(service.GetBlocks(user.ID))})
+}
+
+// CreateBlock
+// @Summary      屏蔽用户
+// @Description  屏蔽一名用户，双方无法再单聊，也无法将对方拉入群聊，对方在群聊中的消息对当前用户隐藏
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string              true  "token"
+// @Param        data     body      model.CreateBlockQ  true  "被屏蔽的用户ID"
+// @Success      200      {object}  model.CommonA       "是否成功，返回信息"
+// @Router       /api/v1/users/blocks [post]
+func CreateBlock(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CreateBlockQ{}).(*model.CreateBlockQ)
+	if _, notFound := service.GetUserByID(data.UserID); notFound || data.UserID == user.ID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "用户不存在"})
+		return
+	}
+	if err := service.BlockUser(user.ID, data.UserID); err != nil {
+		global.LOG.Panic("CreateBlock: block user error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "屏蔽成功"})
+}
+
+// DeleteBlock
+// @Summary      取消屏蔽
+// @Description  取消对一名用户的屏蔽
+// @Tags         私信模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "被屏蔽的用户ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/users/blocks/{id} [delete]
+func DeleteBlock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	user := utils.SolveUser(c)
+	if err := service.UnblockUser(user.ID, id); err != nil {
+		global.LOG.Panic("DeleteBlock: unblock user error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "取消屏蔽成功"})
+}
@@ -0,0 +1,277 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/service"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"net/http"
+	"strconv"
+)
+
+// GetNotification
+// @Summary      获取通知
+// @Description  分页获取当前用户的站内通知，按时间降序排列
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true   "token"
+// @Param        page     query     int                     true   "位于第几页，页数从1开始"
+// @Param        unread   query     bool                    false  "是否只获取未读通知"
+// @Success      200      {object}  model.GetNotificationA  "是否成功，返回信息，通知总数，通知列表"
+// @Router       /api/v1/notifications [get]
+func GetNotification(c *gin.Context) {
+	// 获取请求数据
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		c.JSON(http.StatusOK, model.GetNotificationA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+	// 获取通知
+	user := utils.SolveUser(c)
+	notifications, total := service.GetNotificationsByPage(user.ID, page, unreadOnly)
+	c.JSON(http.StatusOK, model.GetNotificationA{
+		Success:       true,
+		Total:         total,
+		Notifications: service.BuildNotifications(notifications)})
+}
+
+// GetUnreadNotificationCount
+// @Summary      获取未读通知数
+// @Description  获取当前用户的未读通知数
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                             true  "token"
+// @Success      200      {object}  model.GetUnreadNotificationCountA  "是否成功，返回信息，未读通知数"
+// @Router       /api/v1/notifications/unread-count [get]
+func GetUnreadNotificationCount(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetUnreadNotificationCountA{Success: true, Count: service.CountUnreadNotifications(user.ID)})
+}
+
+// ReadNotification
+// @Summary      标记通知为已读
+// @Description  将当前用户的若干通知标记为已读，不指定通知时全部标记为已读
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                   true  "token"
+// @Param        data     body      model.ReadNotificationQ  true  "通知ID列表，为空表示全部"
+// @Success      200      {object}  model.CommonA            "是否成功，返回信息"
+// @Router       /api/v1/notifications/read [put]
+func ReadNotification(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.ReadNotificationQ{}).(*model.ReadNotificationQ)
+	if err := service.ReadNotifications(user.ID, data.IDs); err != nil {
+		global.LOG.Panic("ReadNotification: read notifications error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "标记成功"})
+}
+
+// GetNotificationPreference
+// @Summary      获取通知偏好
+// @Description  获取当前用户对每种通知类型是否接收的偏好
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                            true  "token"
+// @Success      200      {object}  model.GetNotificationPreferenceA  "是否成功，返回信息，通知偏好列表"
+// @Router       /api/v1/notifications/preferences [get]
+func GetNotificationPreference(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetNotificationPreferenceA{Success: true, Preferences: service.GetNotificationPreferences(user.ID)})
+}
+
+// UpdateNotificationPreference
+// @Summary      更新通知偏好
+// @Description  设置当前用户是否接收某些类型的通知，未列出的类型保持不变
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                               true  "token"
+// @Param        data     body      model.UpdateNotificationPreferenceQ  true  "通知类型与是否接收"
+// @Success      200      {object}  model.CommonA                        "是否成功，返回信息"
+// @Router       /api/v1/notifications/preferences [put]
+func UpdateNotificationPreference(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.UpdateNotificationPreferenceQ{}).(*model.UpdateNotificationPreferenceQ)
+	// 通知类型合法性判定
+	valid := make(map[int]bool)
+	for _, notificationType := range service.NotificationTypes {
+		valid[notificationType] = true
+	}
+	for _, preference := range data.Preferences {
+		if !valid[preference.Type] {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "通知类型非法"})
+			return
+		}
+	}
+	if err := service.UpdateNotificationPreferences(user.ID, data.Preferences); err != nil {
+		global.LOG.Panic("UpdateNotificationPreference: update preferences error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新通知偏好成功"})
+}
+
+// GetSubscription
+// @Summary      获取订阅
+// @Description  获取当前用户订阅的所有帖子与板块
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Success      200      {object}  model.GetSubscriptionA  "是否成功，返回信息，订阅列表"
+// @Router       /api/v1/notifications/subscriptions [get]
+func GetSubscription(c *gin.Context) {
+	user := utils.SolveUser(c)
+	c.JSON(http.StatusOK, model.GetSubscriptionA{
+		Success:       true,
+		Subscriptions: service.BuildSubscriptions(service.GetSubscriptions(user.ID))})
+}
+
+// CreateSubscription
+// @Summary      订阅帖子或板块
+// @Description  订阅帖子后接收帖子的新评论，订阅板块后接收板块的新帖子，用户必须能查看该帖子或是板块所在组织的成员；已订阅时返回原有的订阅
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                     true  "token"
+// @Param        data     body      model.CreateSubscriptionQ  true  "组织ID，订阅类型，帖子ID或板块类型"
+// @Success      200      {object}  model.CreateSubscriptionA  "是否成功，返回信息，订阅ID"
+// @Router       /api/v1/notifications/subscriptions [post]
+func CreateSubscription(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.CreateSubscriptionQ{}).(*model.CreateSubscriptionQ)
+	// 订阅对象的存在性判定
+	subscription := model.Subscription{UserID: user.ID, OrgID: data.OrgID, TargetType: data.TargetType, TargetID: data.TargetID}
+	switch data.TargetType {
+	case service.SubscriptionTypePost:
+		post, notFound := service.GetPostByID(data.TargetID)
+		if notFound {
+			c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: false, Message: "帖子不存在"})
+			return
+		}
+		if !service.JudgePostReadPermission(post, c) {
+			c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: false, Message: "用户没有权限查看该帖子"})
+			return
+		}
+		subscription.OrgID = post.OrgID
+	case service.SubscriptionTypeBoard:
+		if !service.IsValidBoard(data.TargetID) {
+			c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: false, Message: "板块不存在"})
+			return
+		}
+		if ok, err := service.IsUserInThisOrganization(user.ID, subscription.OrgID); !ok || err != nil {
+			c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: false, Message: "用户不是该组织成员"})
+			return
+		}
+	default:
+		c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: false, Message: "订阅类型非法"})
+		return
+	}
+	subscription, err := service.Subscribe(subscription)
+	if err != nil {
+		global.LOG.Panic("CreateSubscription: create subscription error")
+	}
+	c.JSON(http.StatusOK, model.CreateSubscriptionA{Success: true, Message: "订阅成功", ID: subscription.ID})
+}
+
+// DeleteSubscription
+// @Summary      取消订阅
+// @Description  取消当前用户的一个订阅
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "订阅ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/notifications/subscriptions/{id} [delete]
+func DeleteSubscription(c *gin.Context) {
+	// 订阅存在性判定
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	user := utils.SolveUser(c)
+	subscription, notFound := service.GetSubscriptionByID(id)
+	if notFound || subscription.UserID != user.ID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "订阅不存在"})
+		return
+	}
+	if err := service.Unsubscribe(subscription); err != nil {
+		global.LOG.Panic("DeleteSubscription: delete subscription error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "取消订阅成功"})
+}
+
+// Unsubscribe
+// @Summary      通过邮件退订
+// @Description  通过摘要邮件中的退订链接取消订阅，无需登录，链接由服务器签名
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        id     query     int            true  "订阅ID"
+// @Param        token  query     string         true  "退订链接的签名"
+// @Success      200    {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/notifications/unsubscribe [get]
+func Unsubscribe(c *gin.Context) {
+	// 签名合法性判定
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil || !utils.ValidateUnsubscribeToken(id, c.Query("token")) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "退订链接无效"})
+		return
+	}
+	// 订阅已被取消时同样视为退订成功
+	subscription, notFound := service.GetSubscriptionByID(id)
+	if !notFound {
+		if err := service.Unsubscribe(subscription); err != nil {
+			global.LOG.Panic("Unsubscribe: delete subscription error")
+		}
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "退订成功"})
+}
+
+// GetDigestSetting
+// @Summary      获取摘要邮件设置
+// @Description  获取当前用户订阅动态邮件的发送频率
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                   true  "token"
+// @Success      200      {object}  model.GetDigestSettingA  "是否成功，返回信息，发送频率，上次发送摘要的时间"
+// @Router       /api/v1/notifications/digest [get]
+func GetDigestSetting(c *gin.Context) {
+	user := utils.SolveUser(c)
+	setting := service.GetDigestSetting(user.ID)
+	lastSentAt := ""
+	if setting.LastSentAt != nil {
+		lastSentAt = setting.LastSentAt.Format("2006-01-02 15:04:05")
+	}
+	c.JSON(http.StatusOK, model.GetDigestSettingA{Success: true, Frequency: setting.Frequency, LastSentAt: lastSentAt})
+}
+
+// UpdateDigestSetting
+// @Summary      更新摘要邮件设置
+// @Description  设置订阅动态邮件的发送频率，即时发送时每条动态单独发送一封邮件，每日与每周发送时汇总为一封摘要邮件
+// @Tags         通知模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                      true  "token"
+// @Param        data     body      model.UpdateDigestSettingQ  true  "发送频率"
+// @Success      200      {object}  model.CommonA               "是否成功，返回信息"
+// @Router       /api/v1/notifications/digest [put]
+func UpdateDigestSetting(c *gin.Context) {
+	user := utils.SolveUser(c)
+	data := utils.BindJsonData(c, &model.UpdateDigestSettingQ{}).(*model.UpdateDigestSettingQ)
+	if !service.IsValidDigestFrequency(data.Frequency) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "发送频率非法"})
+		return
+	}
+	if err := service.UpdateDigestSetting(user.ID, data.Frequency); err != nil {
+		global.LOG.Panic("UpdateDigestSetting: update digest setting error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新摘要邮件设置成功"})
+}
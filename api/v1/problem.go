@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // CreateProblem
@@ -61,6 +62,10 @@ func CreateProblem(c *gin.Context) {
 		_ = service.DeleteProblemByID(problem.ID)
 		global.LOG.Panic("CreateProblem: save problem error")
 	}
+	if err := service.RecordProblemVersion(problem); err != nil {
+		global.LOG.Warn("CreateProblem: record problem version error")
+	}
+	service.IndexProblem(problem)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建题目成功"})
 }
 
@@ -88,7 +93,7 @@ func GetProblem(c *gin.Context) {
 		return
 	}
 	// 用户权限判定
-	if !service.JudgeReadPermission(problem.OrgID, problem.Readable, problem.Creator, c) {
+	if !service.JudgeProblemReadPermission(problem, c) {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您对该题目无可读权限"})
 		return
 	}
@@ -105,6 +110,45 @@ func GetProblem(c *gin.Context) {
 
 }
 
+// GetProblemFile
+// @Summary      获取题目文件
+// @Description  获取题目某一版本的描述、输入或输出文件，用户必须有该题目的读权限
+// @Tags         评测模块
+// @Accept       json
+// @Produce      octet-stream
+// @Param        x-token  header    string         true  "token"
+// @Param        folder   path      string         true  "题目文件夹，格式为{题目ID}_{版本号}"
+// @Param        kind     path      string         true  "文件类型，description、input或output"
+// @Success      200      {file}    file           "题目文件"
+// @Router       /api/v1/resource/problem/{folder}/{kind} [get]
+func GetProblemFile(c *gin.Context) {
+	// 获取请求参数
+	parts := strings.Split(c.Param("folder"), "_")
+	kind := c.Param("kind")
+	if len(parts) != 2 || (kind != "description" && kind != "input" && kind != "output") {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	id, err1 := strconv.ParseUint(parts[0], 10, 64)
+	version, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 题目的存在性判定
+	problem, notFound := service.GetProblemByID(id)
+	if notFound || version <= 0 || version > problem.Version {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该题目的信息"})
+		return
+	}
+	// 用户权限判定
+	if !service.JudgeProblemReadPermission(problem, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您对该题目无可读权限"})
+		return
+	}
+	c.File(filepath.Join(global.VP.GetString("problem_path"), service.GetProblemFileFolder(problem.ID, version), kind))
+}
+
 // UpdateProblem
 // @Summary      更新题目
 // @Description  更新一个题目的信息，并自动更新题目版本
@@ -160,6 +204,10 @@ func UpdateProblem(c *gin.Context) {
 		return
 	}
 	// 成功更新题目
+	if err := service.RecordProblemVersion(problem); err != nil {
+		global.LOG.Warn("UpdateProblem: record problem version error")
+	}
+	service.IndexProblem(problem)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新题目成功"})
 }
 
@@ -190,6 +238,7 @@ func DeleteProblem(c *gin.Context) {
 	if err = global.DB.Delete(&problem).Error; err != nil {
 		global.LOG.Panic("DeleteProblem: delete problem error")
 	}
+	service.RemoveSearchDocument(service.SearchTypeProblem, problem.ID)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除题目成功"})
 
 }
@@ -281,7 +330,7 @@ func GetProblemList(c *gin.Context) {
 
 // UploadProblemRecord
 // @Summary      上传评测结果
-// @Description  上传一个题目的评测结果，用户必须有该题目的读权限(0 AC, 1 WA, 2 TLE, 3 RE)
+// @Description  上传一个题目的评测结果，用户必须有该题目的读权限(0 AC, 1 WA, 2 TLE, 3 RE)；虚拟参赛时间窗口外的提交不计入虚拟参赛成绩
 // @Tags         评测模块
 // @Accept       multipart/form-data
 // @Produce      json
@@ -310,7 +359,7 @@ func UploadProblemRecord(c *gin.Context) {
 	}
 	// 用户权限判定
 	user := utils.SolveUser(c)
-	if !service.JudgeReadPermission(problem.OrgID, problem.Readable, problem.Creator, c) {
+	if !service.JudgeProblemReadPermission(problem, c) {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您对该题目无可读权限"})
 		return
 	}
@@ -320,6 +369,10 @@ func UploadProblemRecord(c *gin.Context) {
 		UserID:    user.ID,
 		ProblemID: problem.ID,
 		Language:  data.Language}
+	// 在虚拟参赛时间窗口内的提交标记为该次虚拟参赛，窗口外的提交不计入虚拟参赛成绩
+	if virtual, notFound := service.GetActiveVirtualContest(user.ID, problem.ID); !notFound {
+		result.VirtualID = virtual.ID
+	}
 	if err = global.DB.Create(&result).Error; err != nil {
 		global.LOG.Warn("UploadProblemRecord: judge problem error")
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "上传评测结果失败"})
@@ -331,6 +384,14 @@ func UploadProblemRecord(c *gin.Context) {
 		global.DB.Delete(&result)
 		global.LOG.Panic("UploadProblemRecord: save judge code error")
 	}
+	service.PublishVerdictEvent(result)
+	service.Notify(model.Notification{
+		UserID:   user.ID,
+		Type:     service.NotificationTypeJudge,
+		ActorID:  user.ID,
+		TargetID: problem.ID,
+		SourceID: result.ID,
+		Content:  problem.Name + "的评测结果：" + service.GetResultName(result.Result)})
 	// 返回响应
 	c.JSON(http.StatusOK, model.CommonA{Success: true})
 }
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Search
+// @Summary      全文搜索
+// @Description  在当前用户所在组织的题目描述、教程内容、教程课时与帖子内容中全文搜索，支持中文，结果按相关度降序排列，只返回当前用户可读的结果；单个字符的关键字按子串匹配，结果按更新时间降序排列；结果总数至多统计100条，超出时hasMore为true
+// @Tags         搜索模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true   "token"
+// @Param        keyWord  query     string         true   "搜索关键字"
+// @Param        type     query     int            false  "结果类型，1为题目，2为教程，3为帖子，4为教程课时，为空表示不限类型"
+// @Param        page     query     int            true   "位于第几页，页数从1开始"
+// @Success      200      {object}  model.SearchA  "是否成功，返回信息，结果总数，结果列表"
+// @Router       /api/v1/search [get]
+func Search(c *gin.Context) {
+	// 获取请求数据
+	keyWord := strings.TrimSpace(c.Query("keyWord"))
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 || keyWord == "" {
+		c.JSON(http.StatusOK, model.SearchA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	types := make([]int, 0)
+	if c.Query("type") != "" {
+		docType, err := strconv.Atoi(c.Query("type"))
+		if err != nil {
+			c.JSON(http.StatusOK, model.SearchA{Success: false, Message: "请求参数非法"})
+			return
+		}
+		types = append(types, docType)
+	}
+	// 搜索并过滤出当前用户可读的结果，在过滤后的结果上分页
+	hits, total, hasMore, err := service.SearchReadable(keyWord, types, page, 10, c)
+	if err != nil {
+		global.LOG.Panic("Search: search error")
+	}
+	c.JSON(http.StatusOK, model.SearchA{Success: true, Total: total, HasMore: hasMore, Hits: hits})
+}
@@ -243,6 +243,16 @@ func CreateInvitation(c *gin.Context) {
 	if err != nil {
 		global.LOG.Panic("CreateInvitation: create invitation error")
 	}
+	content := "邀请你加入组织" + org.Name
+	if data.IsAdmin {
+		content += "并成为管理员"
+	}
+	service.Notify(model.Notification{
+		UserID:   user.ID,
+		Type:     service.NotificationTypeInvitation,
+		ActorID:  utils.SolveUser(c).ID,
+		TargetID: org.ID,
+		Content:  content})
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "成功发送邀请"})
 }
 
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/phoenix-next/phoenix-server/global"
@@ -13,17 +14,18 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // CreateTutorial
 // @Summary      创建教程
-// @Description  创建一个教程，一个教程目前等价于一个markdown文件
+// @Description  创建一个教程，教程可以是单个markdown文件，也可以是包含markdown文档与图片的zip教程包，教程包至多包含1000个条目，且不能包含其他类型的文件
 // @Tags         教程模块
 // @Accept       multipart/form-data
 // @Produce      json
 // @Param        x-token  header    string                  true  "token"
-// @Param        file     formData  file                   true  "教程文件"
-// @Param        data     body      model.CreateTutorialQ  true  "组织ID，教程名称，教程简介，可读权限，可写权限"
+// @Param        file     formData  file                   true  "教程文件或zip教程包"
+// @Param        data     body      model.CreateTutorialQ  true  "组织ID，教程名称，教程简介，可读权限，可写权限，教程包主文档路径"
 // @Success      200      {object}  model.CommonA          "是否成功，返回信息"
 // @Router       /api/v1/tutorials [post]
 func CreateTutorial(c *gin.Context) {
@@ -48,11 +50,19 @@ func CreateTutorial(c *gin.Context) {
 		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "创建教程失败"})
 		return
 	}
-	if err := c.SaveUploadedFile(data.File, filepath.Join(global.VP.GetString("tutorial_path"), service.GetTutorialFileName(tutorial))); err != nil {
+	mainFile, err := service.SaveTutorialFile(c, data.File, tutorial.ID, tutorial.Version, data.MainFile)
+	if err != nil {
 		// 回滚数据库
 		_ = service.DeleteTutorialByID(tutorial.ID)
-		global.LOG.Panic(err)
+		global.LOG.Warn("CreateTutorial: save tutorial file error: ", err)
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "保存教程文件失败，请检查教程包是否合法"})
+		return
 	}
+	version := model.TutorialVersion{TutorialID: tutorial.ID, Version: tutorial.Version, AuthorID: user.ID, Note: "创建教程", MainFile: mainFile}
+	if err := service.CreateTutorialVersion(&version); err != nil {
+		global.LOG.Panic("CreateTutorial: create tutorial version error")
+	}
+	service.IndexTutorial(tutorial)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建教程成功"})
 }
 
@@ -64,7 +74,7 @@ func CreateTutorial(c *gin.Context) {
 // @Produce      json
 // @Param        x-token  header    string              true  "token"
 // @Param        id       path      int                    true  "教程ID"
-// @Success      200      {object}  model.GetTutorialA  "组织ID，创建者ID，创建者名称，教程名称，教程简介，教程版本，教程下载路径"
+// @Success      200      {object}  model.GetTutorialA  "组织ID，创建者ID，创建者名称，教程名称，教程简介，教程版本，教程下载路径，教程包下载路径，章节与课时目录，编辑锁状态"
 // @Router       /api/v1/tutorials/{id} [get]
 func GetTutorial(c *gin.Context) {
 	// 获取请求中的数据
@@ -85,6 +95,7 @@ func GetTutorial(c *gin.Context) {
 		return
 	}
 	// 返回响应
+	tutorialPath, bundlePath := service.GetTutorialPaths(tutorial.ID, tutorial.Version, service.GetTutorialMainFile(tutorial.ID, tutorial.Version))
 	c.JSON(http.StatusOK, model.GetTutorialA{
 		Success:      true,
 		OrgID:        tutorial.OrgID,
@@ -93,54 +104,77 @@ func GetTutorial(c *gin.Context) {
 		Name:         tutorial.Name,
 		Profile:      tutorial.Profile,
 		Version:      tutorial.Version,
-		TutorialPath: "resource/tutorial/" + service.GetTutorialFileName(tutorial)})
+		TutorialPath: tutorialPath,
+		BundlePath:   bundlePath,
+		Chapters:     service.GetTutorialOutline(tutorial.ID),
+		Lock:         service.GetTutorialLock(tutorial)})
 }
 
 // UpdateTutorial
 // @Summary      更新教程
-// @Description  更新一个教程的信息，并自动更新教程版本
+// @Description  更新一个教程的信息，并自动更新教程版本，原版本保留为历史版本；若修改所基于的版本不是当前版本，或教程正在被他人编辑，则更新失败
 // @Tags         教程模块
 // @Accept       multipart/form-data
 // @Produce      json
 // @Param        x-token  header    string                 true  "token"
 // @Param        id       path      int                 true  "教程ID"
-// @Param        file     formData  file                   true  "教程文件"
-// @Param        data     body      model.UpdateTutorialQ  true  "教程名称，教程简介"
-// @Success      200      {object}  model.CommonA          "是否成功，返回信息"
+// @Param        file     formData  file                   true  "教程文件或zip教程包"
+// @Param        data     body      model.UpdateTutorialQ  true  "教程名称，教程简介，修改说明，所基于的版本，教程包主文档路径"
+// @Success      200      {object}  model.UpdateTutorialA  "是否成功，返回信息，是否冲突，教程当前版本"
 // @Router       /api/v1/tutorials/{id} [put]
 func UpdateTutorial(c *gin.Context) {
 	// 获取数据
 	var data model.UpdateTutorialQ
-	if err := c.ShouldBind(&data); err != nil {
-		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+	if err := c.ShouldBind(&data); err != nil || utf8.RuneCountInString(data.Note) > service.TutorialNoteMaxLength {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "请求参数非法"})
 		return
 	}
 	// 教程不存在的情况
 	tutorial, notFound := service.GetTutorialByID(data.ID)
 	if notFound {
-		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该教程的信息"})
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "找不到该教程的信息"})
 		return
 	}
 	// 当前用户没有写权限的情况
+	user := utils.SolveUser(c)
 	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
-		c.JSON(http.StatusOK, model.GetTutorialA{Success: false, Message: "您无可写权限"})
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	// 教程被他人锁定，或修改所基于的版本已过时的情况
+	if service.IsTutorialLockedByOthers(tutorial, user.ID) {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程正在被他人编辑", Version: tutorial.Version})
+		return
+	}
+	if data.BaseVersion != tutorial.Version {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程已被他人更新，请基于最新版本修改", IsConflict: true, Version: tutorial.Version})
 		return
 	}
-	// 用户具有写权限，则更新数据库，并保存原教程以备回滚数据库之需
+	// 先在数据库中占用新版本，防止并发更新互相覆盖，并记录新版本的作者与修改说明
 	tutorialOrigin := tutorial
-	if err := service.UpdateTutorial(&tutorial, &data); err != nil {
+	tutorial.Name, tutorial.Profile, tutorial.Version = data.Name, data.Profile, tutorial.Version+1
+	if err := service.UpdateTutorial(&tutorial, data.BaseVersion, user.ID, data.Note); errors.Is(err, service.ErrTutorialVersionConflict) {
+		latest, _ := service.GetTutorialByID(tutorial.ID)
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程已被他人更新，请基于最新版本修改", IsConflict: true, Version: latest.Version})
+		return
+	} else if err != nil {
 		global.LOG.Panic("UpdateTutorial: save tutorial error")
 	}
-	// 保存文件失败，回滚数据库
-	filePath := filepath.Join(global.VP.GetString("tutorial_path"), service.GetTutorialFileName(tutorial))
-	if err := c.SaveUploadedFile(data.File, filePath); err != nil {
-		_ = service.SaveTutorial(&tutorialOrigin)
-		global.LOG.Warn("save tutorial " + tutorialOrigin.Name + " file error")
-		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "保存教程文件失败"})
+	// 以新版本保存文件，原版本文件保留作为历史版本，保存失败时回滚数据库
+	mainFile, err := service.SaveTutorialFile(c, data.File, tutorial.ID, tutorial.Version, data.MainFile)
+	if err == nil && mainFile != "" {
+		err = service.SetTutorialMainFile(tutorial.ID, tutorial.Version, mainFile)
+	}
+	if err != nil {
+		_ = service.RollbackTutorial(&tutorialOrigin, tutorial.Version)
+		service.RemoveTutorialFiles(tutorial.ID, tutorial.Version)
+		global.LOG.Warn("save tutorial "+tutorial.Name+" file error: ", err)
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "保存教程文件失败，请检查教程包是否合法", Version: tutorialOrigin.Version})
 		return
 	}
+	service.IndexTutorial(tutorial)
 	// 返回响应
-	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新成功"})
+	c.JSON(http.StatusOK, model.UpdateTutorialA{Success: true, Message: "更新成功", Version: tutorial.Version})
 }
 
 // DeleteTutorial
@@ -168,9 +202,12 @@ func DeleteTutorial(c *gin.Context) {
 	}
 	// TODO: 用户权限判定
 	// 成功删除教程
+	lessons := service.GetTutorialLessons(id)
 	if err = service.DeleteTutorialByID(id); err != nil {
 		global.LOG.Panic("DeleteTutorial: delete tutorial error")
 	}
+	service.RemoveSearchDocument(service.SearchTypeTutorial, id)
+	service.RemoveLessonDocuments(lessons)
 	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除教程成功"})
 }
 
@@ -262,3 +299,866 @@ func GetTutorialList(c *gin.Context) {
 	// 返回响应
 	c.JSON(http.StatusOK, model.GetTutorialListA{Success: true, TutorialList: finalTutorials, Total: len(tutorials)})
 }
+
+// LockTutorial
+// @Summary      获取教程编辑锁
+// @Description  获取或续期教程的编辑锁，有效期为10分钟，持有期间其他用户无法修改教程及其章节与课时，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string               true  "token"
+// @Param        id       path      int                  true  "教程ID"
+// @Success      200      {object}  model.LockTutorialA  "是否成功，返回信息，编辑锁状态"
+// @Router       /api/v1/tutorials/{id}/lock [post]
+func LockTutorial(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.LockTutorialA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.LockTutorialA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.LockTutorialA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	// 获取编辑锁，并返回最新的编辑锁状态
+	ok, err := service.LockTutorial(tutorial.ID, user.ID)
+	if err != nil {
+		global.LOG.Panic("LockTutorial: lock tutorial error")
+	}
+	tutorial, _ = service.GetTutorialByID(tutorial.ID)
+	if !ok {
+		c.JSON(http.StatusOK, model.LockTutorialA{Success: false, Message: "教程正在被他人编辑", Lock: service.GetTutorialLock(tutorial)})
+		return
+	}
+	c.JSON(http.StatusOK, model.LockTutorialA{Success: true, Message: "获取编辑锁成功", Lock: service.GetTutorialLock(tutorial)})
+}
+
+// UnlockTutorial
+// @Summary      释放教程编辑锁
+// @Description  编辑锁的持有者释放编辑锁，组织管理员可以强制释放他人的编辑锁
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string         true  "token"
+// @Param        id       path      int            true  "教程ID"
+// @Success      200      {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/lock [delete]
+func UnlockTutorial(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, user.ID) && !service.IsOrganizationAdmin(user.ID, tutorial.OrgID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您没有持有该教程的编辑锁"})
+		return
+	}
+	// 释放编辑锁
+	if err = service.UnlockTutorial(tutorial.ID); err != nil {
+		global.LOG.Panic("UnlockTutorial: unlock tutorial error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "释放编辑锁成功"})
+}
+
+// GetTutorialVersionList
+// @Summary      获取教程历史版本
+// @Description  获取教程的所有历史版本，包括作者、时间、修改说明与下载路径，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                         true  "token"
+// @Param        id       path      int                            true  "教程ID"
+// @Success      200      {object}  model.GetTutorialVersionListA  "是否成功，返回信息，历史版本列表"
+// @Router       /api/v1/tutorials/{id}/versions [get]
+func GetTutorialVersionList(c *gin.Context) {
+	// 获取请求数据
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetTutorialVersionListA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetTutorialVersionListA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.GetTutorialVersionListA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 获取历史版本与作者名称
+	versions := service.GetTutorialVersions(tutorial.ID)
+	authorIDs := make([]uint64, 0)
+	for _, version := range versions {
+		authorIDs = append(authorIDs, version.AuthorID)
+	}
+	authors := service.GetUsersByIDs(authorIDs)
+	finalVersions := make([]model.TutorialVersionT, 0)
+	for _, version := range versions {
+		tutorialPath, bundlePath := service.GetTutorialPaths(tutorial.ID, version.Version, version.MainFile)
+		finalVersions = append(finalVersions, model.TutorialVersionT{
+			Version:      version.Version,
+			AuthorID:     version.AuthorID,
+			AuthorName:   authors[version.AuthorID].Name,
+			Note:         version.Note,
+			CreatedTime:  version.CreatedTime.Format("2006-01-02 15:04:05"),
+			TutorialPath: tutorialPath,
+			BundlePath:   bundlePath})
+	}
+	c.JSON(http.StatusOK, model.GetTutorialVersionListA{Success: true, Versions: finalVersions})
+}
+
+// GetTutorialDiff
+// @Summary      比较教程版本
+// @Description  以unified diff格式按行比较教程的两个版本，需要教程的可读权限，超过1MB或不是文本的教程文件无法比较
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                  true  "token"
+// @Param        id       path      int                     true  "教程ID"
+// @Param        from     query     int                     true  "原版本"
+// @Param        to       query     int                     true  "新版本"
+// @Success      200      {object}  model.GetTutorialDiffA  "是否成功，返回信息，差异文本"
+// @Router       /api/v1/tutorials/{id}/diff [get]
+func GetTutorialDiff(c *gin.Context) {
+	// 获取请求数据
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	from, err2 := strconv.Atoi(c.Query("from"))
+	to, err3 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 读取两个版本的内容并比较
+	if from < 1 || to < 1 || from > tutorial.Version || to > tutorial.Version {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "教程版本不存在"})
+		return
+	}
+	fromText, err1 := service.ReadTutorialVersionText(tutorial.ID, from)
+	toText, err2 := service.ReadTutorialVersionText(tutorial.ID, to)
+	if errors.Is(err1, service.ErrTutorialNotDiffable) || errors.Is(err2, service.ErrTutorialNotDiffable) {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "教程文件过大或不是文本文件，无法比较"})
+		return
+	}
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: false, Message: "教程版本文件缺失"})
+		return
+	}
+	diff, err := utils.DiffText(fromText, toText, "v"+strconv.Itoa(from), "v"+strconv.Itoa(to), 3)
+	if err != nil {
+		global.LOG.Panic("GetTutorialDiff: diff error")
+	}
+	c.JSON(http.StatusOK, model.GetTutorialDiffA{Success: true, Diff: diff})
+}
+
+// RestoreTutorialVersion
+// @Summary      恢复教程版本
+// @Description  以教程的某个历史版本的内容创建一个新版本，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                         true  "token"
+// @Param        id       path      int                            true  "教程ID"
+// @Param        version  path      int                            true  "要恢复的版本"
+// @Param        data     body      model.RestoreTutorialVersionQ  true  "修改说明，所基于的版本"
+// @Success      200      {object}  model.UpdateTutorialA          "是否成功，返回信息，是否冲突，教程当前版本"
+// @Router       /api/v1/tutorials/{id}/versions/{version}/restore [post]
+func RestoreTutorialVersion(c *gin.Context) {
+	// 获取请求数据
+	var data model.RestoreTutorialVersionQ
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	version, err2 := strconv.Atoi(c.Param("version"))
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 必须指明所基于的版本，修改说明为空时使用默认的修改说明
+	if err := c.ShouldBindJSON(&data); err != nil || data.BaseVersion == 0 ||
+		utf8.RuneCountInString(data.Note) > service.TutorialNoteMaxLength {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程正在被他人编辑", Version: tutorial.Version})
+		return
+	}
+	if version < 1 || version >= tutorial.Version {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程版本不存在", Version: tutorial.Version})
+		return
+	}
+	if data.BaseVersion != tutorial.Version {
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程已被他人更新，请基于最新版本修改", IsConflict: true, Version: tutorial.Version})
+		return
+	}
+	// 先在数据库中占用新版本，再将历史版本的文件复制为新版本
+	if data.Note == "" {
+		data.Note = "恢复至版本" + strconv.Itoa(version)
+	}
+	tutorialOrigin := tutorial
+	tutorial.Version++
+	if err := service.UpdateTutorial(&tutorial, data.BaseVersion, utils.SolveUser(c).ID, data.Note); errors.Is(err, service.ErrTutorialVersionConflict) {
+		latest, _ := service.GetTutorialByID(tutorial.ID)
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程已被他人更新，请基于最新版本修改", IsConflict: true, Version: latest.Version})
+		return
+	} else if err != nil {
+		global.LOG.Panic("RestoreTutorialVersion: save tutorial error")
+	}
+	mainFile, err := service.CopyTutorialVersion(tutorial.ID, version, tutorial.Version)
+	if err == nil && mainFile != "" {
+		err = service.SetTutorialMainFile(tutorial.ID, tutorial.Version, mainFile)
+	}
+	if err != nil {
+		_ = service.RollbackTutorial(&tutorialOrigin, tutorial.Version)
+		service.RemoveTutorialFiles(tutorial.ID, tutorial.Version)
+		global.LOG.Warn("RestoreTutorialVersion: copy tutorial file error")
+		c.JSON(http.StatusOK, model.UpdateTutorialA{Success: false, Message: "教程版本文件缺失", Version: tutorialOrigin.Version})
+		return
+	}
+	service.IndexTutorial(tutorial)
+	c.JSON(http.StatusOK, model.UpdateTutorialA{Success: true, Message: "恢复版本成功", Version: tutorial.Version})
+}
+
+// CreateTutorialChapter
+// @Summary      创建教程章节
+// @Description  在教程中创建一个章节，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                        true  "token"
+// @Param        id       path      int                           true  "教程ID"
+// @Param        data     body      model.CreateTutorialChapterQ  true  "章节标题，章节位置"
+// @Success      200      {object}  model.CommonA                 "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/chapters [post]
+func CreateTutorialChapter(c *gin.Context) {
+	// 获取请求数据
+	var data model.CreateTutorialChapterQ
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || c.ShouldBindJSON(&data) != nil || !service.IsValidTutorialTitle(data.Title) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	// 创建章节
+	chapter := model.TutorialChapter{TutorialID: tutorial.ID, Title: data.Title, Position: data.Position}
+	if err = service.SaveTutorialChapter(&chapter); err != nil {
+		global.LOG.Panic("CreateTutorialChapter: save chapter error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建章节成功"})
+}
+
+// UpdateTutorialChapter
+// @Summary      更新教程章节
+// @Description  更新教程章节的标题与位置，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token    header    string                        true  "token"
+// @Param        id         path      int                           true  "教程ID"
+// @Param        chapterID  path      int                           true  "章节ID"
+// @Param        data       body      model.UpdateTutorialChapterQ  true  "章节标题，章节位置"
+// @Success      200        {object}  model.CommonA                 "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/chapters/{chapterID} [put]
+func UpdateTutorialChapter(c *gin.Context) {
+	// 获取请求数据
+	var data model.UpdateTutorialChapterQ
+	if err := c.ShouldBindJSON(&data); err != nil || !service.IsValidTutorialTitle(data.Title) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	chapter, ok := service.GetTutorialChapterFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该章节的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(chapter.TutorialID)
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	// 更新章节
+	chapter.Title, chapter.Position = data.Title, data.Position
+	if err := service.SaveTutorialChapter(&chapter); err != nil {
+		global.LOG.Panic("UpdateTutorialChapter: save chapter error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新章节成功"})
+}
+
+// DeleteTutorialChapter
+// @Summary      删除教程章节
+// @Description  删除教程章节及其所有课时，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token    header    string         true  "token"
+// @Param        id         path      int            true  "教程ID"
+// @Param        chapterID  path      int            true  "章节ID"
+// @Success      200        {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/chapters/{chapterID} [delete]
+func DeleteTutorialChapter(c *gin.Context) {
+	// 获取请求数据
+	chapter, ok := service.GetTutorialChapterFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该章节的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(chapter.TutorialID)
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	// 删除章节，并删除章节中课时的索引
+	lessons := make([]model.TutorialLesson, 0)
+	for _, lesson := range service.GetTutorialLessons(tutorial.ID) {
+		if lesson.ChapterID == chapter.ID {
+			lessons = append(lessons, lesson)
+		}
+	}
+	if err := service.DeleteTutorialChapter(&chapter); err != nil {
+		global.LOG.Panic("DeleteTutorialChapter: delete chapter error")
+	}
+	service.RemoveLessonDocuments(lessons)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除章节成功"})
+}
+
+// CreateTutorialLesson
+// @Summary      创建教程课时
+// @Description  在教程的某个章节中创建一个课时，一个课时对应一个markdown文件，并可嵌入若干练习题，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        x-token  header    string                       true  "token"
+// @Param        id       path      int                          true  "教程ID"
+// @Param        file     formData  file                         true  "课时文件"
+// @Param        data     body      model.CreateTutorialLessonQ  true  "章节ID，课时标题，课时位置，练习题ID列表"
+// @Success      200      {object}  model.CommonA                "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/lessons [post]
+func CreateTutorialLesson(c *gin.Context) {
+	// 获取请求数据
+	var data model.CreateTutorialLessonQ
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || c.ShouldBind(&data) != nil || !service.IsValidTutorialTitle(data.Title) || data.File == nil {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程与章节的存在性判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if chapter, notFound := service.GetTutorialChapterByID(data.ChapterID); notFound || chapter.TutorialID != tutorial.ID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该章节的信息"})
+		return
+	}
+	// 用户权限判定
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	if !service.JudgeLessonProblems(data.ProblemIDs, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "练习题不存在或不可读"})
+		return
+	}
+	// 创建课时，保存文件失败时回滚数据库
+	lesson := model.TutorialLesson{
+		TutorialID: tutorial.ID,
+		ChapterID:  data.ChapterID,
+		CreatorID:  utils.SolveUser(c).ID,
+		Title:      data.Title,
+		Position:   data.Position,
+		Version:    1}
+	if err = service.SaveTutorialLesson(&lesson, data.ProblemIDs); err != nil {
+		global.LOG.Panic("CreateTutorialLesson: save lesson error")
+	}
+	if err = c.SaveUploadedFile(data.File, filepath.Join(global.VP.GetString("tutorial_path"), service.GetTutorialLessonFileName(lesson))); err != nil {
+		_ = service.DeleteTutorialLesson(&lesson)
+		global.LOG.Panic(err)
+	}
+	service.IndexLesson(lesson, tutorial)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "创建课时成功"})
+}
+
+// GetTutorialLesson
+// @Summary      获取教程课时
+// @Description  获取一个教程课时的信息、下载路径与嵌入的练习题，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token   header    string                    true  "token"
+// @Param        id        path      int                       true  "教程ID"
+// @Param        lessonID  path      int                       true  "课时ID"
+// @Success      200       {object}  model.GetTutorialLessonA  "是否成功，返回信息，课时信息，课时下载路径，练习题列表"
+// @Router       /api/v1/tutorials/{id}/lessons/{lessonID} [get]
+func GetTutorialLesson(c *gin.Context) {
+	// 获取请求数据
+	lesson, ok := service.GetTutorialLessonFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.GetTutorialLessonA{Success: false, Message: "找不到该课时的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(lesson.TutorialID)
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.GetTutorialLessonA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 返回响应
+	c.JSON(http.StatusOK, model.GetTutorialLessonA{
+		Success:    true,
+		ID:         lesson.ID,
+		TutorialID: lesson.TutorialID,
+		ChapterID:  lesson.ChapterID,
+		Title:      lesson.Title,
+		Position:   lesson.Position,
+		Version:    lesson.Version,
+		LessonPath: "resource/tutorial/" + service.GetTutorialLessonFileName(lesson),
+		Problems:   service.GetLessonProblems(lesson.ID, c)})
+}
+
+// UpdateTutorialLesson
+// @Summary      更新教程课时
+// @Description  更新教程课时的信息与嵌入的练习题，若上传了课时文件则自动更新课时版本，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        x-token   header    string                       true   "token"
+// @Param        id        path      int                          true   "教程ID"
+// @Param        lessonID  path      int                          true   "课时ID"
+// @Param        file      formData  file                         false  "课时文件"
+// @Param        data      body      model.UpdateTutorialLessonQ  true   "章节ID，课时标题，课时位置，练习题ID列表"
+// @Success      200       {object}  model.CommonA                "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/lessons/{lessonID} [put]
+func UpdateTutorialLesson(c *gin.Context) {
+	// 获取请求数据
+	var data model.UpdateTutorialLessonQ
+	if err := c.ShouldBind(&data); err != nil || !service.IsValidTutorialTitle(data.Title) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	lesson, ok := service.GetTutorialLessonFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该课时的信息"})
+		return
+	}
+	if chapter, notFound := service.GetTutorialChapterByID(data.ChapterID); notFound || chapter.TutorialID != lesson.TutorialID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该章节的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(lesson.TutorialID)
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	if !service.JudgeLessonProblems(data.ProblemIDs, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "练习题不存在或不可读"})
+		return
+	}
+	// 先保存新版本的课时文件，再更新数据库，保证数据库中的版本总有对应的文件
+	lesson.ChapterID, lesson.Title, lesson.Position = data.ChapterID, data.Title, data.Position
+	if data.File != nil {
+		lesson.Version++
+		if err := c.SaveUploadedFile(data.File, filepath.Join(global.VP.GetString("tutorial_path"), service.GetTutorialLessonFileName(lesson))); err != nil {
+			global.LOG.Warn("save lesson " + lesson.Title + " file error")
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "保存课时文件失败"})
+			return
+		}
+	}
+	if err := service.SaveTutorialLesson(&lesson, data.ProblemIDs); err != nil {
+		global.LOG.Panic("UpdateTutorialLesson: save lesson error")
+	}
+	service.IndexLesson(lesson, tutorial)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新课时成功"})
+}
+
+// DeleteTutorialLesson
+// @Summary      删除教程课时
+// @Description  删除一个教程课时，需要教程的可写权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token   header    string         true  "token"
+// @Param        id        path      int            true  "教程ID"
+// @Param        lessonID  path      int            true  "课时ID"
+// @Success      200       {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/lessons/{lessonID} [delete]
+func DeleteTutorialLesson(c *gin.Context) {
+	// 获取请求数据
+	lesson, ok := service.GetTutorialLessonFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该课时的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(lesson.TutorialID)
+	if !service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您无可写权限"})
+		return
+	}
+	if service.IsTutorialLockedByOthers(tutorial, utils.SolveUser(c).ID) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程正在被他人编辑"})
+		return
+	}
+	// 删除课时
+	if err := service.DeleteTutorialLesson(&lesson); err != nil {
+		global.LOG.Panic("DeleteTutorialLesson: delete lesson error")
+	}
+	service.RemoveSearchDocument(service.SearchTypeLesson, lesson.ID)
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除课时成功"})
+}
+
+// CompleteTutorialLesson
+// @Summary      学完教程课时
+// @Description  记录当前用户已学完课时内容，课时中的练习题均通过后该课时才算完成，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token   header    string         true  "token"
+// @Param        id        path      int            true  "教程ID"
+// @Param        lessonID  path      int            true  "课时ID"
+// @Success      200       {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/lessons/{lessonID}/completion [post]
+func CompleteTutorialLesson(c *gin.Context) {
+	// 获取请求数据
+	lesson, ok := service.GetTutorialLessonFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该课时的信息"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(lesson.TutorialID)
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 记录学习进度
+	if err := service.CompleteLesson(utils.SolveUser(c).ID, lesson); err != nil {
+		global.LOG.Panic("CompleteTutorialLesson: save lesson progress error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "已记录学习进度"})
+}
+
+// GetTutorialProgress
+// @Summary      获取教程学习进度
+// @Description  获取当前用户在教程中的学习进度，包括每个课时是否学完、练习题通过情况，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                      true  "token"
+// @Param        id       path      int                         true  "教程ID"
+// @Success      200      {object}  model.GetTutorialProgressA  "是否成功，返回信息，总体进度，各课时进度"
+// @Router       /api/v1/tutorials/{id}/progress [get]
+func GetTutorialProgress(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetTutorialProgressA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetTutorialProgressA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.GetTutorialProgressA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 计算学习进度
+	lessonProblems, problemIDs := service.GetTutorialProblemIDs(tutorial.ID)
+	read := service.GetReadLessons([]uint64{user.ID}, tutorial.ID)
+	accepted := service.GetAcceptedProblems([]uint64{user.ID}, problemIDs)
+	progress, lessons := service.CalculateTutorialProgress(service.GetTutorialLessons(tutorial.ID), lessonProblems, read[user.ID], accepted[user.ID])
+	c.JSON(http.StatusOK, model.GetTutorialProgressA{Success: true, Progress: progress, Lessons: lessons})
+}
+
+// GetMemberProgress
+// @Summary      获取组织成员学习进度
+// @Description  教程所属组织的管理员获取组织内所有成员在该教程中的学习进度
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                    true  "token"
+// @Param        id       path      int                       true  "教程ID"
+// @Success      200      {object}  model.GetMemberProgressA  "是否成功，返回信息，各成员的学习进度"
+// @Router       /api/v1/tutorials/{id}/member-progress [get]
+func GetMemberProgress(c *gin.Context) {
+	// 获取请求数据
+	user := utils.SolveUser(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetMemberProgressA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetMemberProgressA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.IsOrganizationAdmin(user.ID, tutorial.OrgID) {
+		c.JSON(http.StatusOK, model.GetMemberProgressA{Success: false, Message: "用户没有管理员权限"})
+		return
+	}
+	// 批量计算所有成员的学习进度
+	members := service.GetOrganizationMember(tutorial.OrgID)
+	userIDs := make([]uint64, 0)
+	for _, member := range members {
+		userIDs = append(userIDs, member.ID)
+	}
+	lessons := service.GetTutorialLessons(tutorial.ID)
+	lessonProblems, problemIDs := service.GetTutorialProblemIDs(tutorial.ID)
+	read := service.GetReadLessons(userIDs, tutorial.ID)
+	accepted := service.GetAcceptedProblems(userIDs, problemIDs)
+	finalMembers := make([]model.MemberProgressT, 0)
+	for _, member := range members {
+		progress, _ := service.CalculateTutorialProgress(lessons, lessonProblems, read[member.ID], accepted[member.ID])
+		finalMembers = append(finalMembers, model.MemberProgressT{UserID: member.ID, UserName: member.Name, Progress: progress})
+	}
+	c.JSON(http.StatusOK, model.GetMemberProgressA{Success: true, Members: finalMembers})
+}
+
+// CreateTutorialComment
+// @Summary      创建教程评论
+// @Description  在教程下发表评论或回复评论，顶层评论可锚定到某个版本的标题或行范围，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                        true  "token"
+// @Param        id       path      int                           true  "教程ID"
+// @Param        data     body      model.CreateTutorialCommentQ  true  "被回复的评论ID，评论内容，锚定的版本，锚定的标题，锚定的行范围"
+// @Success      200      {object}  model.CommonA                 "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/comments [post]
+func CreateTutorialComment(c *gin.Context) {
+	// 获取请求数据
+	var data model.CreateTutorialCommentQ
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || c.ShouldBindJSON(&data) != nil || strings.TrimSpace(data.Content) == "" {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	comment := model.TutorialComment{
+		TutorialID: tutorial.ID,
+		ParentID:   data.ParentID,
+		CreatorID:  utils.SolveUser(c).ID,
+		Content:    data.Content,
+		Version:    tutorial.Version}
+	if data.ParentID != 0 {
+		// 回复评论的情况，被回复的评论需属于该教程
+		if parent, notFound := service.GetTutorialCommentByID(data.ParentID); notFound || parent.TutorialID != tutorial.ID {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "被回复的评论不存在"})
+			return
+		}
+	} else if data.AnchorHeading != "" || data.AnchorStartLine != 0 {
+		// 锚定评论的情况，记录锚定部分内容的哈希值
+		if data.Version != 0 {
+			comment.Version = data.Version
+		}
+		if comment.Version < 1 || comment.Version > tutorial.Version {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程版本不存在"})
+			return
+		}
+		content, err := service.ReadTutorialVersion(tutorial.ID, comment.Version)
+		if err != nil {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "教程版本文件缺失"})
+			return
+		}
+		section, ok := service.GetAnchorSection(content, data.AnchorHeading, data.AnchorStartLine, data.AnchorEndLine)
+		if !ok {
+			c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "锚定的标题或行范围不存在"})
+			return
+		}
+		comment.AnchorHeading, comment.AnchorStartLine, comment.AnchorEndLine = data.AnchorHeading, data.AnchorStartLine, data.AnchorEndLine
+		if data.AnchorHeading != "" {
+			comment.AnchorStartLine, comment.AnchorEndLine = 0, 0
+		}
+		comment.AnchorHash = service.HashAnchorSection(section)
+	}
+	// 创建评论
+	if err = global.DB.Create(&comment).Error; err != nil {
+		global.LOG.Panic("CreateTutorialComment: create comment error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "评论成功"})
+}
+
+// GetTutorialComment
+// @Summary      获取教程评论
+// @Description  以树形结构获取教程的所有评论，锚定部分在之后的版本中被修改的评论会被标记为已过时，需要教程的可读权限
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token  header    string                     true  "token"
+// @Param        id       path      int                        true  "教程ID"
+// @Success      200      {object}  model.GetTutorialCommentA  "是否成功，返回信息，当前用户是否可以管理评论，评论树"
+// @Router       /api/v1/tutorials/{id}/comments [get]
+func GetTutorialComment(c *gin.Context) {
+	// 获取请求数据
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.GetTutorialCommentA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	// 教程的存在性与用户权限判定
+	tutorial, notFound := service.GetTutorialByID(id)
+	if notFound {
+		c.JSON(http.StatusOK, model.GetTutorialCommentA{Success: false, Message: "找不到该教程的信息"})
+		return
+	}
+	if !service.JudgeReadPermission(tutorial.OrgID, tutorial.Readable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.GetTutorialCommentA{Success: false, Message: "您没有可读权限"})
+		return
+	}
+	// 返回评论树
+	c.JSON(http.StatusOK, model.GetTutorialCommentA{
+		Success:     true,
+		CanModerate: service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c),
+		Comments:    service.BuildTutorialCommentTree(tutorial, service.GetTutorialComments(tutorial.ID))})
+}
+
+// UpdateTutorialComment
+// @Summary      更新教程评论
+// @Description  评论者更新自己的评论内容，锚定位置不可修改
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token    header    string                        true  "token"
+// @Param        id         path      int                           true  "教程ID"
+// @Param        commentID  path      int                           true  "评论ID"
+// @Param        data       body      model.UpdateTutorialCommentQ  true  "评论内容"
+// @Success      200        {object}  model.CommonA                 "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/comments/{commentID} [put]
+func UpdateTutorialComment(c *gin.Context) {
+	// 获取请求数据
+	var data model.UpdateTutorialCommentQ
+	if err := c.ShouldBindJSON(&data); err != nil || strings.TrimSpace(data.Content) == "" {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "请求参数非法"})
+		return
+	}
+	comment, ok := service.GetTutorialCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	if comment.CreatorID != utils.SolveUser(c).ID {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "没有权限更新评论"})
+		return
+	}
+	// 更新评论
+	if err := global.DB.Model(&comment).Update("content", data.Content).Error; err != nil {
+		global.LOG.Panic("UpdateTutorialComment: update comment error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "更新评论成功"})
+}
+
+// DeleteTutorialComment
+// @Summary      删除教程评论
+// @Description  删除教程评论及其所有回复，删除者可以是评论者或具有教程可写权限的用户
+// @Tags         教程模块
+// @Accept       json
+// @Produce      json
+// @Param        x-token    header    string         true  "token"
+// @Param        id         path      int            true  "教程ID"
+// @Param        commentID  path      int            true  "评论ID"
+// @Success      200        {object}  model.CommonA  "是否成功，返回信息"
+// @Router       /api/v1/tutorials/{id}/comments/{commentID} [delete]
+func DeleteTutorialComment(c *gin.Context) {
+	// 获取请求数据
+	comment, ok := service.GetTutorialCommentFromParam(c)
+	if !ok {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "评论不存在"})
+		return
+	}
+	// 用户权限判定
+	tutorial, _ := service.GetTutorialByID(comment.TutorialID)
+	if comment.CreatorID != utils.SolveUser(c).ID &&
+		!service.JudgeWritePermission(tutorial.OrgID, tutorial.Writable, tutorial.CreatorID, c) {
+		c.JSON(http.StatusOK, model.CommonA{Success: false, Message: "没有权限删除评论"})
+		return
+	}
+	// 删除评论
+	if err := service.DeleteTutorialComment(comment); err != nil {
+		global.LOG.Panic("DeleteTutorialComment: delete comment error")
+	}
+	c.JSON(http.StatusOK, model.CommonA{Success: true, Message: "删除评论成功"})
+}
@@ -176,7 +176,7 @@ func UpdateUser(c *gin.Context) {
 // @Produce      json
 // @Param        x-token  header    string          true  "token"
 // @Param        id       path      int             true  "用户ID"
-// @Success      200      {object}  model.GetUserA  "是否成功，返回信息，用户名，用户邮箱，用户头像，用户简介"
+// @Success      200      {object}  model.GetUserA  "是否成功，返回信息，用户名，用户邮箱，用户头像，用户简介，用户积分，积分变化历史"
 // @Router       /api/v1/users/{id} [get]
 func GetUser(c *gin.Context) {
 	// 获取请求数据
@@ -191,13 +191,32 @@ func GetUser(c *gin.Context) {
 		c.JSON(http.StatusOK, model.GetUserA{Success: false, Message: "找不到对应的用户"})
 		return
 	}
+	// 获取用户的积分变化历史
+	histories := service.GetUserRatingHistory(user.ID)
+	contestIDs := make([]uint64, 0)
+	for _, history := range histories {
+		contestIDs = append(contestIDs, history.ContestID)
+	}
+	contestNames := service.GetContestNames(contestIDs)
+	ratingHistory := make([]model.RatingT, 0)
+	for _, history := range histories {
+		ratingHistory = append(ratingHistory, model.RatingT{
+			ContestID:   history.ContestID,
+			ContestName: contestNames[history.ContestID],
+			Rank:        history.Rank,
+			OldRating:   history.OldRating,
+			NewRating:   history.NewRating,
+			CreatedTime: history.CreatedTime.Format("2006-01-02 15:04:05")})
+	}
 	// 返回响应
 	c.JSON(http.StatusOK, model.GetUserA{
-		Success: true,
-		Name:    user.Name,
-		Email:   user.Email,
-		Avatar:  user.Avatar,
-		Profile: user.Profile})
+		Success:       true,
+		Name:          user.Name,
+		Email:         user.Email,
+		Avatar:        user.Avatar,
+		Profile:       user.Profile,
+		Rating:        user.Rating,
+		RatingHistory: ratingHistory})
 }
 
 // GetUserOrganization
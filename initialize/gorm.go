@@ -59,15 +59,43 @@ func InitMySQL() *gorm.DB {
 	err = db.AutoMigrate(
 		&model.User{},
 		&model.Problem{},
+		&model.ProblemVersion{},
 		&model.Tutorial{},
 		&model.Contest{},
 		&model.Organization{},
 		&model.Comment{},
+		&model.Reaction{},
+		&model.Report{},
+		&model.ForumRevision{},
+		&model.Notification{},
+		&model.NotificationPreference{},
+		&model.Subscription{},
+		&model.DigestSetting{},
+		&model.Conversation{},
+		&model.Message{},
+		&model.ConversationMember{},
+		&model.UserBlock{},
 		&model.Captcha{},
 		&model.Post{},
 		&model.Result{},
 		&model.ContestProblem{},
 		&model.Invitation{},
+		&model.VirtualContest{},
+		&model.Clarification{},
+		&model.ClarificationRead{},
+		&model.RatingHistory{},
+		&model.ContestExport{},
+		&model.ContestTemplate{},
+		&model.ContestTemplateProblem{},
+		&model.ExamSession{},
+		&model.ExamLog{},
+		&model.TutorialVersion{},
+		&model.TutorialComment{},
+		&model.TutorialChapter{},
+		&model.TutorialLesson{},
+		&model.TutorialLessonProblem{},
+		&model.LessonProgress{},
+		&model.SearchDocument{},
 	)
 	if err != nil {
 		panic("初始化失败：更新MySQL数据库内容失败")
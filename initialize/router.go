@@ -19,7 +19,7 @@ func InitRouter(r *gin.Engine) {
 	// 跨域配置
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
-	config.AllowHeaders = append(config.AllowHeaders, "x-token")
+	config.AllowHeaders = append(config.AllowHeaders, "x-token", "x-exam-session")
 	r.Use(cors.New(config))
 	// 是否开启api文档页面
 	if global.VP.GetBool("server.docs") {
@@ -37,6 +37,7 @@ func InitRouter(r *gin.Engine) {
 		rawRouter.POST("/captcha", v1.CreateCaptcha)
 		rawRouter.POST("/tokens", v1.CreateToken)
 		rawRouter.POST("/password", v1.ResetPassword)
+		rawRouter.GET("/notifications/unsubscribe", v1.Unsubscribe)
 	}
 	// 图片资源服务器
 	imageRouter := rawRouter.Group("/resource")
@@ -44,16 +45,26 @@ func InitRouter(r *gin.Engine) {
 		imageRouter.Static("/image", global.VP.GetString("image_path"))
 	}
 
-	// 除了登录模块和头像资源之外，都需要身份认证
+	// 考试会话模块，仅需身份认证，不受考试会话限制
+	examRouter := rawRouter.Group("/")
+	examRouter.Use(middleware.AuthRequired())
+	{
+		examRouter.POST("/contests/:id/sessions", v1.StartExamSession)
+	}
+
+	// 除了登录模块和头像资源之外，都需要身份认证，考试期间还需要考试会话
 	basicRouter := rawRouter.Group("/")
-	basicRouter.Use(middleware.AuthRequired())
+	basicRouter.Use(middleware.AuthRequired(), middleware.ExamRequired())
+
+	// 推送模块
+	basicRouter.GET("/events", v1.GetEventStream)
 
 	// 静态资源服务器
 	resourceRouter := basicRouter.Group("/resource")
 	{
-		resourceRouter.Static("/problem", global.VP.GetString("problem_path"))
-		resourceRouter.Static("/tutorial", global.VP.GetString("tutorial_path"))
-		resourceRouter.Static("/code", global.VP.GetString("code_path"))
+		resourceRouter.GET("/problem/:folder/:kind", v1.GetProblemFile)
+		resourceRouter.Group("/tutorial", middleware.ExamForbidden(), middleware.TutorialResource()).Static("/", global.VP.GetString("tutorial_path"))
+		resourceRouter.Group("/code", middleware.ExamForbidden()).Static("/", global.VP.GetString("code_path"))
 		resourceRouter.POST("/image", v1.UploadImage)
 	}
 	// 用户模块
@@ -64,6 +75,9 @@ func InitRouter(r *gin.Engine) {
 		userRouter.GET("/organizations", v1.GetUserOrganization)
 		userRouter.GET("/invitations", v1.GetUserInvitation)
 		userRouter.DELETE("/organizations/:id", v1.QuitOrganization)
+		userRouter.GET("/blocks", v1.GetBlock)
+		userRouter.POST("/blocks", v1.CreateBlock)
+		userRouter.DELETE("/blocks/:id", v1.DeleteBlock)
 	}
 	// 评测模块
 	problemRouter := basicRouter.Group("/problems")
@@ -91,9 +105,13 @@ func InitRouter(r *gin.Engine) {
 		teamRouter.POST("/:id/admins", v1.UpdateOrganizationAdmin)
 		teamRouter.DELETE("/:id/admins/:adminID", v1.DeleteOrganizationAdmin)
 		teamRouter.GET("/:id/problems", v1.GetOrganizationProblem)
+		teamRouter.POST("/:id/templates", v1.CreateContestTemplate)
+		teamRouter.GET("/:id/templates", v1.GetContestTemplate)
+		teamRouter.DELETE("/:id/templates/:templateID", v1.DeleteContestTemplate)
 	}
 	// 论坛模块
 	forumRouter := basicRouter.Group("")
+	forumRouter.Use(middleware.ExamForbidden())
 	{
 		forumRouter.POST("/posts", v1.CreatePost)
 		forumRouter.DELETE("/posts/:id", v1.DeletePost)
@@ -104,9 +122,62 @@ func InitRouter(r *gin.Engine) {
 		forumRouter.PUT("/comments/:id", v1.UpdateComment)
 		forumRouter.DELETE("/comments/:id", v1.DeleteComment)
 		forumRouter.GET("posts/:id/comments", v1.GetComment)
+		forumRouter.GET("/comments/:id/replies", v1.GetCommentReply)
+		forumRouter.POST("/posts/:id/reactions", v1.ReactPost)
+		forumRouter.POST("/comments/:id/reactions", v1.ReactComment)
+		forumRouter.PUT("/posts/:id/accepted-answer", v1.AcceptAnswer)
+		forumRouter.PUT("/posts/:id/moderation", v1.ModeratePost)
+		forumRouter.POST("/posts/:id/reports", v1.ReportPost)
+		forumRouter.POST("/comments/:id/reports", v1.ReportComment)
+		forumRouter.GET("/reports", v1.GetReport)
+		forumRouter.PUT("/reports/:id", v1.HandleReport)
+		forumRouter.GET("/deleted-posts", v1.GetDeletedPost)
+		forumRouter.GET("/deleted-comments", v1.GetDeletedComment)
+		forumRouter.POST("/posts/:id/restore", v1.RestorePost)
+		forumRouter.POST("/comments/:id/restore", v1.RestoreComment)
+		forumRouter.GET("/posts/:id/revisions", v1.GetPostRevision)
+		forumRouter.GET("/posts/:id/revisions/diff", v1.GetPostRevisionDiff)
+		forumRouter.GET("/comments/:id/revisions", v1.GetCommentRevision)
+		forumRouter.GET("/comments/:id/revisions/diff", v1.GetCommentRevisionDiff)
+		forumRouter.GET("/problems/:id/posts", v1.GetProblemPost)
+		forumRouter.POST("/problems/:id/posts", v1.CreateProblemPost)
+	}
+	// 通知模块
+	notificationRouter := basicRouter.Group("/notifications")
+	notificationRouter.Use(middleware.ExamForbidden())
+	{
+		notificationRouter.GET("", v1.GetNotification)
+		notificationRouter.GET("/unread-count", v1.GetUnreadNotificationCount)
+		notificationRouter.PUT("/read", v1.ReadNotification)
+		notificationRouter.GET("/preferences", v1.GetNotificationPreference)
+		notificationRouter.PUT("/preferences", v1.UpdateNotificationPreference)
+		notificationRouter.GET("/subscriptions", v1.GetSubscription)
+		notificationRouter.POST("/subscriptions", v1.CreateSubscription)
+		notificationRouter.DELETE("/subscriptions/:id", v1.DeleteSubscription)
+		notificationRouter.GET("/digest", v1.GetDigestSetting)
+		notificationRouter.PUT("/digest", v1.UpdateDigestSetting)
+	}
+	// 私信模块
+	messageRouter := basicRouter.Group("/conversations")
+	messageRouter.Use(middleware.ExamForbidden())
+	{
+		messageRouter.GET("", v1.GetConversation)
+		messageRouter.POST("", v1.CreateConversation)
+		messageRouter.GET("/unread-count", v1.GetUnreadMessageCount)
+		messageRouter.GET("/:id/messages", v1.GetMessage)
+		messageRouter.POST("/:id/messages", v1.SendMessage)
+		messageRouter.PUT("/:id/read", v1.ReadConversation)
+		messageRouter.DELETE("/:id/members/me", v1.LeaveConversation)
+	}
+	// 搜索模块
+	searchRouter := basicRouter.Group("/search")
+	searchRouter.Use(middleware.ExamForbidden())
+	{
+		searchRouter.GET("", v1.Search)
 	}
 	// 教程模块
 	tutorialRouter := basicRouter.Group("/tutorials")
+	tutorialRouter.Use(middleware.ExamForbidden())
 	{
 		tutorialRouter.GET("", v1.GetTutorialList)
 		tutorialRouter.POST("", v1.CreateTutorial)
@@ -114,6 +185,25 @@ func InitRouter(r *gin.Engine) {
 		tutorialRouter.GET("/:id", v1.GetTutorial)
 		tutorialRouter.PUT("/:id", v1.UpdateTutorial)
 		tutorialRouter.GET("/:id/version", v1.GetTutorialVersion)
+		tutorialRouter.POST("/:id/lock", v1.LockTutorial)
+		tutorialRouter.DELETE("/:id/lock", v1.UnlockTutorial)
+		tutorialRouter.GET("/:id/versions", v1.GetTutorialVersionList)
+		tutorialRouter.GET("/:id/diff", v1.GetTutorialDiff)
+		tutorialRouter.POST("/:id/versions/:version/restore", v1.RestoreTutorialVersion)
+		tutorialRouter.POST("/:id/chapters", v1.CreateTutorialChapter)
+		tutorialRouter.PUT("/:id/chapters/:chapterID", v1.UpdateTutorialChapter)
+		tutorialRouter.DELETE("/:id/chapters/:chapterID", v1.DeleteTutorialChapter)
+		tutorialRouter.POST("/:id/lessons", v1.CreateTutorialLesson)
+		tutorialRouter.GET("/:id/lessons/:lessonID", v1.GetTutorialLesson)
+		tutorialRouter.PUT("/:id/lessons/:lessonID", v1.UpdateTutorialLesson)
+		tutorialRouter.DELETE("/:id/lessons/:lessonID", v1.DeleteTutorialLesson)
+		tutorialRouter.POST("/:id/lessons/:lessonID/completion", v1.CompleteTutorialLesson)
+		tutorialRouter.GET("/:id/progress", v1.GetTutorialProgress)
+		tutorialRouter.GET("/:id/member-progress", v1.GetMemberProgress)
+		tutorialRouter.POST("/:id/comments", v1.CreateTutorialComment)
+		tutorialRouter.GET("/:id/comments", v1.GetTutorialComment)
+		tutorialRouter.PUT("/:id/comments/:commentID", v1.UpdateTutorialComment)
+		tutorialRouter.DELETE("/:id/comments/:commentID", v1.DeleteTutorialComment)
 	}
 	// 比赛模块
 	contestRouter := basicRouter.Group("/contests")
@@ -123,6 +213,17 @@ func InitRouter(r *gin.Engine) {
 		contestRouter.GET("/:id", v1.GetContest)
 		contestRouter.DELETE("/:id", v1.DeleteContest)
 		contestRouter.PUT("/:id", v1.UpdateContest)
+		contestRouter.POST("/:id/virtual", v1.StartVirtualContest)
+		contestRouter.GET("/:id/standings", v1.GetContestStandings)
+		contestRouter.POST("/:id/finalization", v1.FinalizeContest)
+		contestRouter.POST("/:id/clone", v1.CloneContest)
+		contestRouter.GET("/:id/exam-logs", v1.GetExamLog)
+		contestRouter.POST("/:id/exports", v1.CreateContestExport)
+		contestRouter.GET("/:id/exports/:exportID", v1.GetContestExport)
+		contestRouter.GET("/:id/exports/:exportID/file", v1.DownloadContestExport)
+		contestRouter.POST("/:id/clarifications", v1.CreateClarification)
+		contestRouter.GET("/:id/clarifications", v1.GetClarification)
+		contestRouter.PUT("/:id/clarifications/:clarificationID", v1.AnswerClarification)
 	}
 }
 
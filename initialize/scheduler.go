@@ -0,0 +1,33 @@
+package initialize
+
+import (
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/service"
+	"time"
+)
+
+// InitScheduler 启动后台定时任务，每小时执行一次，另有部分任务仅在启动时执行一次
+func InitScheduler() {
+	go runTask("RebuildSearchIndex", service.RebuildSearchIndex)
+	go runTask("RepairCommentTree", service.RepairCommentTree)
+	go runTask("ResumeContestExports", service.ResumeContestExports)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for {
+			runTask("CreateUpcomingContests", service.CreateUpcomingContests)
+			runTask("PurgeDeletedForum", service.PurgeDeletedForum)
+			runTask("SendForumDigests", service.SendForumDigests)
+			<-ticker.C
+		}
+	}()
+}
+
+// runTask 执行一个定时任务，任务中的panic不会导致服务器退出
+func runTask(name string, task func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			global.LOG.Warn("定时任务"+name+"执行失败：", err)
+		}
+	}()
+	task()
+}
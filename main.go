@@ -21,6 +21,8 @@ func main() {
 	global.VP = initialize.InitViper()
 	global.LOG = initialize.InitLogger()
 	global.DB = initialize.InitMySQL()
+	// 启动定时任务
+	initialize.InitScheduler()
 	// 创建Router
 	if !global.VP.GetBool("server.debug") {
 		gin.SetMode(gin.ReleaseMode)
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/service"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"net/http"
+	"time"
+)
+
+// ExamRequired 考试期间，考生只能在允许的IP段内、使用考试会话访问服务器
+// 没有正在进行的考试时仅读取缓存，会话的活动时间按心跳间隔更新
+func ExamRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := utils.SolveUser(c)
+		for _, contest := range service.GetRunningExams(user.ID) {
+			if !utils.IsIPAllowed(c.ClientIP(), contest.AllowedIPs) {
+				abortExam(c, contest.ID, user.ID, "IP不在考试允许的范围内")
+				return
+			}
+			session, notFound := service.GetExamSession(contest.ID, user.ID)
+			if notFound || session.Key != c.GetHeader("x-exam-session") {
+				abortExam(c, contest.ID, user.ID, "考试会话无效，可能已在其他设备登录")
+				return
+			}
+			if service.ShouldRefreshExamSession(session, c.ClientIP()) {
+				session.IP, session.LastActiveTime = c.ClientIP(), time.Now()
+				_ = service.SaveExamSession(&session)
+			}
+		}
+	}
+}
+
+// ExamForbidden 考试期间，考生无法访问该路由
+func ExamForbidden() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := utils.SolveUser(c)
+		if exams := service.GetRunningExams(user.ID); len(exams) > 0 {
+			abortExam(c, exams[0].ID, user.ID, "考试期间禁止访问")
+		}
+	}
+}
+
+// abortExam 拦截考试期间的访问，并记录供监考者查阅
+func abortExam(c *gin.Context, contestID uint64, uid uint64, reason string) {
+	service.CreateExamLog(contestID, uid, c.ClientIP(), c.Request.URL.Path, reason)
+	c.JSON(http.StatusOK, gin.H{"success": false, "message": reason})
+	c.Abort()
+}
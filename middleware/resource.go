@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/service"
+	"path"
+	"strings"
+)
+
+// TutorialResource 教程资源中只有图片按原类型返回，markdown文档与没有扩展名的教程文件按纯文本返回，
+// 其他文件(如教程包)只能作为附件下载，并禁止浏览器推测内容类型，防止上传的文件在服务器域名下执行脚本
+func TutorialResource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		name := c.Request.URL.Path
+		switch {
+		case service.IsTutorialImageFile(name):
+		case service.IsTutorialMarkdownFile(name) || !strings.Contains(path.Base(name), "."):
+			c.Header("Content-Type", "text/plain; charset=utf-8")
+		default:
+			c.Header("Content-Disposition", "attachment")
+		}
+	}
+}
@@ -15,30 +15,135 @@ type ProblemT struct {
 	Result      int    `json:"result"`
 }
 
+type ContestProblemT struct {
+	ProblemID   uint64 `json:"problemID"`
+	ProblemName string `json:"problemName"`
+	Label       string `json:"label"`
+	Title       string `json:"title"` // 题目在比赛中显示的标题，未设置时为题目原名称
+	Position    int    `json:"position"`
+	Score       int    `json:"score"`
+	Difficulty  int    `json:"difficulty"`
+	Result      int    `json:"result"`
+}
+
+type ContestProblemQ struct {
+	ProblemID uint64 `json:"problemID"`
+	Label     string `json:"label"` // 题目在比赛中的编号，为空则按顺序自动生成A、B、C等
+	Title     string `json:"title"` // 题目在比赛中显示的标题，为空则使用题目原名称
+	Score     int    `json:"score"` // 题目在OI赛制下的分值
+}
+
+type StandingProblemT struct {
+	ProblemID    uint64 `json:"problemID"`
+	Accepted     bool   `json:"accepted"`
+	Tries        int    `json:"tries"`        // 首次通过前的错误提交次数
+	AcceptedTime int    `json:"acceptedTime"` // 首次通过的时刻，为距离比赛开始的分钟数
+}
+
+type StandingT struct {
+	Rank      int                `json:"rank"`
+	UserID    uint64             `json:"userID"`
+	UserName  string             `json:"userName"`
+	Solved    int                `json:"solved"`
+	Penalty   int                `json:"penalty"`   // 罚时，单位为分钟
+	IsVirtual bool               `json:"isVirtual"` // 是否为虚拟参赛的成绩
+	Problems  []StandingProblemT `json:"problems"`
+}
+
+type ClarificationT struct {
+	ID             uint64 `json:"id"`
+	ProblemID      uint64 `json:"problemID"` // 提问针对的题目ID，为0表示针对整场比赛
+	CreatorID      uint64 `json:"creatorID"`
+	CreatorName    string `json:"creatorName"`
+	Question       string `json:"question"`
+	Answer         string `json:"answer"`
+	IsPublic       bool   `json:"isPublic"`
+	IsAnnouncement bool   `json:"isAnnouncement"`
+	UpdatedAt      string `json:"updatedAt"`
+}
+
+type ExportSubmissionT struct {
+	ID          uint64 `json:"id"`
+	UserID      uint64 `json:"userID"`
+	UserName    string `json:"userName"`
+	ProblemID   uint64 `json:"problemID"`
+	Label       string `json:"label"`
+	Result      int    `json:"result"` // 0 AC , 1 WA , 2 TLE, 3 RE
+	Language    string `json:"language"`
+	CodeFile    string `json:"codeFile"` // 代码文件在归档中的路径，代码文件缺失时为空
+	CreatedTime string `json:"createdTime"`
+}
+
+type ExportProblemT struct {
+	ProblemID uint64 `json:"problemID"`
+	Label     string `json:"label"`
+	Title     string `json:"title"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+	Score     int    `json:"score"`
+	Folder    string `json:"folder"` // 题目文件在归档中的目录
+}
+
+type ContestTemplateT struct {
+	ID           uint64   `json:"id"`
+	Name         string   `json:"name"`
+	Profile      string   `json:"profile"`
+	Readable     int      `json:"readable"`
+	IsRated      bool     `json:"isRated"`
+	Weekday      int      `json:"weekday"`
+	StartTime    string   `json:"startTime"` // 比赛开始时刻，格式为15:04
+	EndTime      string   `json:"endTime"`   // 比赛结束时刻，格式为15:04
+	ProblemCount int      `json:"problemCount"`
+	ProblemIDs   []uint64 `json:"problemIDs"`
+	Count        int      `json:"count"`
+	NextTime     string   `json:"nextTime"` // 下一场比赛的开始时间
+}
+
+type ExamLogT struct {
+	UserID      uint64 `json:"userID"`
+	UserName    string `json:"userName"`
+	IP          string `json:"ip"`
+	Path        string `json:"path"`
+	Reason      string `json:"reason"`
+	CreatedTime string `json:"createdTime"`
+}
+
 type CreateContestQ struct {
-	OrgID      uint64    `json:"orgID"`
-	Name       string    `json:"name"`
-	Profile    string    `json:"profile"`
-	Readable   int       `json:"readable"`
-	StartTime  time.Time `json:"startTime"`
-	EndTime    time.Time `json:"endTime"`
-	ProblemIDs []uint64  `json:"problemIDs"`
+	OrgID           uint64            `json:"orgID"`
+	Name            string            `json:"name"`
+	Profile         string            `json:"profile"`
+	Readable        int               `json:"readable"`
+	StartTime       time.Time         `json:"startTime"`
+	EndTime         time.Time         `json:"endTime"`
+	IsRated         bool              `json:"isRated"`
+	PublishProblems bool              `json:"publishProblems"` // 比赛结束后是否公开比赛题目，比赛结束前题目对非管理员隐藏
+	IsExam          bool              `json:"isExam"`          // 是否为考试模式
+	ExamKickOld     bool              `json:"examKickOld"`     // 考试模式下其他设备登录时，是否踢出原设备，否则拒绝新设备
+	AllowedIPs      string            `json:"allowedIPs"`      // 考试模式下允许访问的IP段，以逗号分隔的CIDR，为空表示不限制
+	ProblemIDs      []uint64          `json:"problemIDs"`      // 题目ID列表，仅在Problems为空时使用
+	Problems        []ContestProblemQ `json:"problems"`        // 按顺序排列的题目列表
 }
 
 type GetContestA struct {
-	Success   bool       `json:"success"`
-	Message   string     `json:"message"`
-	Name      string     `json:"name"`
-	Profile   string     `json:"profile"`
-	StartTime string     `json:"startTime"`
-	EndTime   string     `json:"endTime"`
-	Problem   []ProblemT `json:"problem"`
+	Success          bool              `json:"success"`
+	Message          string            `json:"message"`
+	Name             string            `json:"name"`
+	Profile          string            `json:"profile"`
+	StartTime        string            `json:"startTime"`
+	EndTime          string            `json:"endTime"`
+	Problem          []ContestProblemT `json:"problem"`
+	IsRated          bool              `json:"isRated"`
+	IsFinalized      bool              `json:"isFinalized"`
+	VirtualStartTime string            `json:"virtualStartTime"` // 当前用户虚拟参赛的开始时间，未虚拟参赛时为空
+	VirtualEndTime   string            `json:"virtualEndTime"`   // 当前用户虚拟参赛的结束时间，未虚拟参赛时为空
 }
 
 type UpdateContestQ struct {
-	Name       string   `json:"name"`
-	Profile    string   `json:"profile"`
-	ProblemIDs []uint64 `json:"problemIDs"`
+	Name       string            `json:"name"`
+	Profile    string            `json:"profile"`
+	IsRated    *bool             `json:"isRated"`    // 是否计分，为空表示不修改
+	ProblemIDs []uint64          `json:"problemIDs"` // 题目ID列表，仅在Problems为空时使用
+	Problems   []ContestProblemQ `json:"problems"`   // 按顺序排列的题目列表
 }
 
 type GetContestListA struct {
@@ -48,6 +153,87 @@ type GetContestListA struct {
 	ContestList []ContestT `json:"contestList"`
 }
 
+type StartVirtualContestA struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+type GetContestStandingsA struct {
+	Success   bool        `json:"success"`
+	Message   string      `json:"message"`
+	Elapsed   int         `json:"elapsed"` // 榜单对应的比赛已进行时间，单位为分钟
+	Standings []StandingT `json:"standings"`
+}
+
+type CloneContestQ struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+type CreateContestTemplateQ struct {
+	Name         string   `json:"name"`
+	Profile      string   `json:"profile"`
+	Readable     int      `json:"readable"`
+	IsRated      bool     `json:"isRated"`
+	Weekday      int      `json:"weekday"`      // 每周的第几天举办比赛，0为周日，6为周六
+	StartTime    string   `json:"startTime"`    // 比赛开始时刻，格式为15:04
+	EndTime      string   `json:"endTime"`      // 比赛结束时刻，格式为15:04，需晚于开始时刻
+	ProblemCount int      `json:"problemCount"` // 每场比赛的题目数量
+	ProblemIDs   []uint64 `json:"problemIDs"`   // 题目池，每场比赛按顺序轮换选取题目
+}
+
+type GetContestTemplateA struct {
+	Success   bool               `json:"success"`
+	Message   string             `json:"message"`
+	Templates []ContestTemplateT `json:"templates"`
+}
+
+type StartExamSessionA struct {
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	SessionKey string `json:"sessionKey"` // 考试会话密钥，考试期间的请求需在x-exam-session请求头中携带
+}
+
+type GetExamLogA struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message"`
+	Logs    []ExamLogT `json:"logs"`
+}
+
+type CreateClarificationQ struct {
+	ProblemID uint64 `json:"problemID"` // 提问针对的题目ID，为0表示针对整场比赛
+	Content   string `json:"content"`   // 选手的提问内容，或管理员的公告内容
+}
+
+type AnswerClarificationQ struct {
+	Answer   string `json:"answer"`
+	IsPublic bool   `json:"isPublic"` // 是否向所有参赛者广播该答疑
+}
+
+type GetClarificationA struct {
+	Success        bool             `json:"success"`
+	Message        string           `json:"message"`
+	IsAdmin        bool             `json:"isAdmin"`
+	Clarifications []ClarificationT `json:"clarifications"`
+}
+
+type CreateContestExportA struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	ExportID uint64 `json:"exportID"`
+}
+
+type GetContestExportA struct {
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	Status       int    `json:"status"`       // 0为生成中，1为已完成，2为生成失败
+	DownloadPath string `json:"downloadPath"` // 归档的下载路径，仅在已完成时有效
+	CreatedTime  string `json:"createdTime"`
+}
+
 type GetOrganizationProblemA struct {
 	Success     bool      `json:"success"`
 	Message     string    `json:"message"`
@@ -1,42 +1,155 @@
 package model
 
-import "time"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 // 论坛模块
 
 // Post 帖子
 type Post struct {
-	ID        uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
-	OrgID     uint64    `gorm:"not null;" json:"orgID"`
-	CreatorID uint64    `gorm:"not null;" json:"creatorID"`
-	Type      int       `gorm:"not null;" json:"type"` // 帖子所属的板块，0为公告板块，1为划水板块，2为讨论板块
-	Title     string    `gorm:"not null;" json:"title"`
-	Content   string    `gorm:"not null;" json:"content"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
+	ID           uint64 `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	OrgID        uint64 `gorm:"not null;" json:"orgID"`
+	CreatorID    uint64 `gorm:"not null;" json:"creatorID"`
+	Type         int    `gorm:"not null;" json:"type"`                        // 帖子所属的板块，0为公告板块，1为划水板块，2为讨论板块
+	ProblemID    uint64 `gorm:"not null; default:0; index;" json:"problemID"` // 所属题目的ID，不为0表示该帖子属于题目讨论而不在组织板块中
+	IsSolution   bool   `gorm:"not null; default:false" json:"isSolution"`    // 是否包含题解，仅对通过题目的用户可见
+	Title        string `gorm:"not null;" json:"title"`
+	Content      string `gorm:"not null;" json:"content"`
+	ContentHTML  string `gorm:"not null;" json:"-"`                      // 内容渲染后的HTML，为空表示尚未缓存
+	CommentCount int    `gorm:"not null; default:0" json:"commentCount"` // 帖子下的评论总数，包括所有回复
+	Score        int    `gorm:"not null; default:0" json:"score"`        // 帖子获得的点赞数
+	// 被采纳为答案的评论ID，为0表示没有采纳答案，仅用于讨论板块
+	AcceptedCommentID uint64         `gorm:"not null; default:0" json:"acceptedCommentID"`
+	LastActivityTime  *time.Time     `json:"lastActivityTime"`                        // 最近一次发帖或评论的时间，为空时以更新时间为准
+	IsPinned          bool           `gorm:"not null; default:false" json:"isPinned"` // 是否置顶，置顶帖子排在板块最前
+	IsLocked          bool           `gorm:"not null; default:false" json:"isLocked"` // 是否锁定，锁定后普通成员无法评论
+	EditCount         int            `gorm:"not null; default:0" json:"editCount"`    // 标题或内容被编辑的次数
+	EditedAt          *time.Time     `json:"editedAt"`                                // 最近一次编辑的时间，为空表示未被编辑
+	DeletedBy         uint64         `gorm:"not null; default:0" json:"deletedBy"`    // 删除者ID
+	DeletedAt         gorm.DeletedAt `gorm:"index;" json:"deletedAt"`                 // 软删除时间，审计期过后永久删除
+	CreatedAt         *time.Time     `gorm:"autoCreateTime;" json:"createdAt"`        // 发帖时间，旧数据为空
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
 }
 
 // Comment 评论
 type Comment struct {
-	ID        uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
-	OrgID     uint64    `gorm:"not null;" json:"orgID"`
-	ToID      uint64    `json:"toID"`                       // 被评论的评论ID，可为空
-	PostID    uint64    `json:"postID"`                     // 帖子的ID，即该评论位于哪个帖子下
-	CreatorID uint64    `gorm:"not null;" json:"creatorID"` // 评论者ID
-	Content   string    `gorm:"not null;" json:"content"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
+	ID          uint64         `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	OrgID       uint64         `gorm:"not null;" json:"orgID"`
+	ToID        uint64         `json:"toID"`                                      // 被评论的评论ID，可为空
+	RootID      uint64         `gorm:"not null; default:0; index;" json:"rootID"` // 所在评论树的顶层评论ID，顶层评论为0
+	Depth       int            `gorm:"not null; default:0" json:"depth"`          // 评论在评论树中的深度，顶层评论为0
+	PostID      uint64         `gorm:"index;" json:"postID"`                      // 帖子的ID，即该评论位于哪个帖子下
+	CreatorID   uint64         `gorm:"not null;" json:"creatorID"`                // 评论者ID
+	Content     string         `gorm:"not null;" json:"content"`
+	ContentHTML string         `gorm:"not null;" json:"-"`                    // 内容渲染后的HTML，为空表示尚未缓存
+	ReplyCount  int            `gorm:"not null; default:0" json:"replyCount"` // 直接回复该评论的评论数
+	Score       int            `gorm:"not null; default:0" json:"score"`      // 评论获得的点赞数
+	EditCount   int            `gorm:"not null; default:0" json:"editCount"`  // 内容被编辑的次数
+	EditedAt    *time.Time     `json:"editedAt"`                              // 最近一次编辑的时间，为空表示未被编辑
+	DeletedBy   uint64         `gorm:"not null; default:0" json:"deletedBy"`  // 删除者ID
+	DeletedAt   gorm.DeletedAt `gorm:"index;" json:"deletedAt"`               // 软删除时间，审计期过后永久删除
+	CreatedAt   *time.Time     `gorm:"autoCreateTime;" json:"createdAt"`      // 评论时间，旧数据为空
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
+}
+
+// Reaction 用户对帖子或评论的表情回应，点赞也是一种表情回应，每个用户对同一对象的同一表情至多回应一次
+type Reaction struct {
+	ID         uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	UserID     uint64    `gorm:"not null; uniqueIndex:idx_reaction;" json:"userID"`
+	TargetType int       `gorm:"not null; uniqueIndex:idx_reaction; index:idx_reaction_target;" json:"targetType"` // 回应对象的类型，1为帖子，2为评论
+	TargetID   uint64    `gorm:"not null; uniqueIndex:idx_reaction; index:idx_reaction_target;" json:"targetID"`
+	Emoji      string    `gorm:"size:32; not null; uniqueIndex:idx_reaction;" json:"emoji"`
+	CreatedAt  time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
+}
+
+// Report 成员对帖子或评论的举报，进入组织的审核队列
+type Report struct {
+	ID         uint64     `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	OrgID      uint64     `gorm:"not null; index;" json:"orgID"`
+	TargetType int        `gorm:"not null;" json:"targetType"` // 举报对象的类型，1为帖子，2为评论
+	TargetID   uint64     `gorm:"not null;" json:"targetID"`
+	ReporterID uint64     `gorm:"not null;" json:"reporterID"`
+	Reason     string     `gorm:"size:256; not null;" json:"reason"`
+	Status     int        `gorm:"not null; default:0" json:"status"` // 0为待处理，1为已处理，2为已驳回
+	HandlerID  uint64     `gorm:"not null; default:0" json:"handlerID"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime; not null;" json:"createdAt"`
+	HandledAt  *time.Time `json:"handledAt"`
+}
+
+// ForumRevision 帖子或评论的修订版本，首次编辑时补记编辑前的原始版本
+type ForumRevision struct {
+	ID         uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	TargetType int       `gorm:"not null; uniqueIndex:idx_forum_revision_version;" json:"targetType"` // 修订对象的类型，1为帖子，2为评论
+	TargetID   uint64    `gorm:"not null; uniqueIndex:idx_forum_revision_version;" json:"targetID"`
+	Version    int       `gorm:"not null; uniqueIndex:idx_forum_revision_version;" json:"version"` // 版本号，从1开始，1为原始版本
+	EditorID   uint64    `gorm:"not null;" json:"editorID"`
+	Title      string    `gorm:"not null;" json:"title"` // 帖子标题，评论为空
+	Content    string    `gorm:"not null;" json:"content"`
+	CreatedAt  time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
 }
 
 // 评测模块
 
 // Contest 比赛
 type Contest struct {
-	ID        uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
-	OrgID     uint64    `gorm:"not null;" json:"orgID"` // 比赛所属的组织ID
-	Name      string    `gorm:"size:32; not null;" json:"name"`
-	Profile   string    `gorm:"not null;" json:"profile"`
-	Readable  int       `gorm:"not null" json:"readable"`
-	StartTime time.Time `json:"startTime"`
-	EndTime   time.Time `json:"endTime"`
+	ID              uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	OrgID           uint64    `gorm:"not null;" json:"orgID"` // 比赛所属的组织ID
+	Name            string    `gorm:"size:32; not null;" json:"name"`
+	Profile         string    `gorm:"not null;" json:"profile"`
+	Readable        int       `gorm:"not null" json:"readable"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	IsRated         bool      `gorm:"not null; default:false" json:"isRated"`         // 是否为计分比赛
+	IsFinalized     bool      `gorm:"not null; default:false" json:"isFinalized"`     // 是否已结算积分
+	PublishProblems bool      `gorm:"not null; default:false" json:"publishProblems"` // 比赛结束后是否公开比赛题目，比赛结束前题目对非管理员隐藏
+	IsExam          bool      `gorm:"not null; default:false" json:"isExam"`          // 是否为考试模式，考试期间组织成员只能有一个会话，且无法访问教程与论坛
+	ExamKickOld     bool      `gorm:"not null; default:false" json:"examKickOld"`     // 考试模式下其他设备登录时，是否踢出原设备，否则拒绝新设备
+	AllowedIPs      string    `json:"allowedIPs"`                                     // 考试模式下允许访问的IP段，以逗号分隔的CIDR，为空表示不限制
+}
+
+// Clarification 比赛答疑，包括选手提问与管理员公告
+type Clarification struct {
+	ID             uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	ContestID      uint64    `gorm:"not null; index;" json:"contestID"`
+	ProblemID      uint64    `json:"problemID"`                  // 提问针对的题目ID，为0表示针对整场比赛
+	CreatorID      uint64    `gorm:"not null;" json:"creatorID"` // 提问者或公告发布者ID
+	Question       string    `json:"question"`
+	Answer         string    `json:"answer"`
+	AnswererID     uint64    `json:"answererID"`                              // 回答者ID，为0表示尚未回答
+	IsPublic       bool      `gorm:"not null; default:false" json:"isPublic"` // 是否对所有参赛者可见
+	IsAnnouncement bool      `gorm:"not null; default:false" json:"isAnnouncement"`
+	CreatedAt      time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
+}
+
+// ContestExport 比赛归档任务
+type ContestExport struct {
+	ID           uint64     `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	ContestID    uint64     `gorm:"not null; index;" json:"contestID"`
+	CreatorID    uint64     `gorm:"not null;" json:"creatorID"`
+	Status       int        `gorm:"not null; default:0" json:"status"` // 0为生成中，1为已完成，2为生成失败
+	CreatedTime  time.Time  `gorm:"autoCreateTime;" json:"createdTime"`
+	FinishedTime *time.Time `json:"finishedTime"` // 归档结束时间，生成中为空
+}
+
+// ContestTemplate 周期性比赛模板，按周自动创建比赛，并从题目池中轮换选取题目
+type ContestTemplate struct {
+	ID            uint64     `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	OrgID         uint64     `gorm:"not null;" json:"orgID"`
+	CreatorID     uint64     `gorm:"not null;" json:"creatorID"`
+	Name          string     `gorm:"size:24; not null;" json:"name"` // 比赛名称前缀，创建的比赛名称为"前缀 #序号"
+	Profile       string     `gorm:"not null;" json:"profile"`
+	Readable      int        `gorm:"not null" json:"readable"`
+	IsRated       bool       `gorm:"not null; default:false" json:"isRated"`
+	Weekday       int        `gorm:"not null;" json:"weekday"`     // 每周的第几天举办比赛，0为周日
+	StartMinute   int        `gorm:"not null;" json:"startMinute"` // 比赛开始时刻，为距离当天零点的分钟数
+	Duration      int        `gorm:"not null;" json:"duration"`    // 比赛时长，单位为分钟
+	ProblemCount  int        `gorm:"not null;" json:"problemCount"`
+	NextIndex     int        `gorm:"not null; default:0" json:"nextIndex"` // 下一场比赛从题目池的哪个位置开始选题
+	Count         int        `gorm:"not null; default:0" json:"count"`     // 已创建的比赛数量
+	LastStartTime *time.Time `json:"lastStartTime"`                        // 最近一次创建的比赛的开始时间，尚未创建比赛时为空
 }
 
 // Problem 题目
@@ -52,6 +165,14 @@ type Problem struct {
 	CreatedTime time.Time `gorm:"autoCreateTime" json:"createdTime"`
 }
 
+// ProblemVersion 题目的版本记录，每次创建或更新题目时记录，用于确定比赛时使用的题目版本
+type ProblemVersion struct {
+	ID          uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	ProblemID   uint64    `gorm:"not null; index;" json:"problemID"`
+	Version     int       `gorm:"not null;" json:"version"`
+	CreatedTime time.Time `gorm:"autoCreateTime" json:"createdTime"`
+}
+
 // 社交模块
 
 // User 用户
@@ -62,6 +183,7 @@ type User struct {
 	Password string    `gorm:"size:128; not null;" json:"password"`
 	Avatar   string    `json:"avatar"`
 	Profile  string    `gorm:"size:256;" json:"profile"`
+	Rating   int       `gorm:"not null; default:1500" json:"rating"` // 用户当前的积分
 	RegTime  time.Time `gorm:"autoCreateTime" json:"regTime"`
 }
 
@@ -88,18 +210,115 @@ type Organization struct {
 
 // Tutorial 教程
 type Tutorial struct {
-	ID        uint64 `gorm:"primary_key;autoIncrement; not null;" json:"id"`
-	OrgID     uint64 `json:"orgID"`
-	CreatorID uint64 `gorm:"not null" json:"creatorID"`
-	Name      string `gorm:"size:32; not null;" json:"name"`
-	Profile   string `gorm:"not null;" json:"profile"`
-	Version   int    `gorm:"not null;" json:"version"`
-	Readable  int    `gorm:"not null" json:"readable"`
-	Writable  int    `gorm:"not null" json:"writable"`
+	ID             uint64     `gorm:"primary_key;autoIncrement; not null;" json:"id"`
+	OrgID          uint64     `json:"orgID"`
+	CreatorID      uint64     `gorm:"not null" json:"creatorID"`
+	Name           string     `gorm:"size:32; not null;" json:"name"`
+	Profile        string     `gorm:"not null;" json:"profile"`
+	Version        int        `gorm:"not null;" json:"version"`
+	Readable       int        `gorm:"not null" json:"readable"`
+	Writable       int        `gorm:"not null" json:"writable"`
+	LockerID       uint64     `gorm:"not null; default:0" json:"lockerID"` // 持有编辑锁的用户ID，为0表示未锁定
+	LockExpireTime *time.Time `json:"lockExpireTime"`                      // 编辑锁的过期时间，过期后其他用户可以获取编辑锁
+}
+
+// TutorialVersion 教程的历史版本，每次创建、更新或恢复教程时记录
+type TutorialVersion struct {
+	ID          uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	TutorialID  uint64    `gorm:"not null; index;" json:"tutorialID"`
+	Version     int       `gorm:"not null;" json:"version"`
+	AuthorID    uint64    `gorm:"not null;" json:"authorID"`
+	Note        string    `gorm:"size:255;" json:"note"`     // 修改说明
+	MainFile    string    `gorm:"size:255;" json:"mainFile"` // 教程包中主文档的相对路径，为空表示该版本为单个文件
+	CreatedTime time.Time `gorm:"autoCreateTime;" json:"createdTime"`
+}
+
+// TutorialComment 教程评论，可锚定到某个版本的标题或行范围
+type TutorialComment struct {
+	ID              uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	TutorialID      uint64    `gorm:"not null; index;" json:"tutorialID"`
+	ParentID        uint64    `gorm:"not null; default:0" json:"parentID"` // 被回复的评论ID，为0表示顶层评论
+	CreatorID       uint64    `gorm:"not null;" json:"creatorID"`
+	Content         string    `gorm:"not null;" json:"content"`
+	Version         int       `gorm:"not null;" json:"version"`                   // 评论时的教程版本
+	AnchorHeading   string    `gorm:"size:255;" json:"anchorHeading"`             // 锚定的标题，为空表示未锚定到标题
+	AnchorStartLine int       `gorm:"not null; default:0" json:"anchorStartLine"` // 锚定的起始行，从1开始，为0表示未锚定到行范围
+	AnchorEndLine   int       `gorm:"not null; default:0" json:"anchorEndLine"`
+	AnchorHash      string    `gorm:"size:64;" json:"anchorHash"` // 评论时锚定部分内容的哈希值，用于判断锚定部分是否已过时
+	CreatedAt       time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime; not null;" json:"updatedAt"`
+}
+
+// TutorialChapter 教程章节，一个教程由若干有序的章节组成
+type TutorialChapter struct {
+	ID         uint64 `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	TutorialID uint64 `gorm:"not null; index;" json:"tutorialID"`
+	Title      string `gorm:"size:64; not null;" json:"title"`
+	Position   int    `gorm:"not null; default:0" json:"position"` // 章节在教程中的位置，从0开始
+}
+
+// TutorialLesson 教程课时，一个章节由若干有序的课时组成，每个课时对应一个markdown文件
+type TutorialLesson struct {
+	ID         uint64 `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	TutorialID uint64 `gorm:"not null; index;" json:"tutorialID"`
+	ChapterID  uint64 `gorm:"not null; index;" json:"chapterID"`
+	CreatorID  uint64 `gorm:"not null;" json:"creatorID"`
+	Title      string `gorm:"size:64; not null;" json:"title"`
+	Position   int    `gorm:"not null; default:0" json:"position"` // 课时在章节中的位置，从0开始
+	Version    int    `gorm:"not null;" json:"version"`
+}
+
+// 搜索模块
+
+// SearchDocument 全文搜索的索引文档，由题目描述、教程内容、课时内容、帖子内容生成
+type SearchDocument struct {
+	ID        uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	Type      int       `gorm:"not null; uniqueIndex:idx_search_target;" json:"type"`     // 文档类型，1为题目，2为教程，3为帖子，4为教程课时
+	TargetID  uint64    `gorm:"not null; uniqueIndex:idx_search_target;" json:"targetID"` // 对应的题目、教程、帖子或课时ID
+	OrgID     uint64    `gorm:"index;" json:"orgID"`
+	Title     string    `gorm:"size:255; index:idx_search_text,class:FULLTEXT,option:WITH PARSER ngram;" json:"title"`
+	Content   string    `gorm:"type:longtext; index:idx_search_text,class:FULLTEXT,option:WITH PARSER ngram;" json:"content"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;" json:"updatedAt"`
+}
+
+// 私信模块
+
+// Conversation 私信会话，单聊会话由PairKey唯一确定，群聊会话的PairKey为空
+type Conversation struct {
+	ID              uint64     `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	CreatorID       uint64     `gorm:"not null;" json:"creatorID"`
+	IsGroup         bool       `gorm:"not null; default:false" json:"isGroup"`
+	Title           string     `gorm:"size:32;" json:"title"`                // 群聊的名称，单聊为空
+	PairKey         *string    `gorm:"size:64; uniqueIndex;" json:"pairKey"` // 单聊双方的用户ID，较小者在前，以-分隔，群聊为空
+	LastMessageID   uint64     `gorm:"not null; default:0" json:"lastMessageID"`
+	LastMessageTime *time.Time `json:"lastMessageTime"` // 最近一条消息的时间，为空表示还没有消息
+	CreatedAt       time.Time  `gorm:"autoCreateTime; not null;" json:"createdAt"`
+}
+
+// Message 私信消息
+type Message struct {
+	ID             uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	ConversationID uint64    `gorm:"not null; index;" json:"conversationID"`
+	SenderID       uint64    `gorm:"not null;" json:"senderID"`
+	Content        string    `gorm:"size:2048; not null;" json:"content"`
+	CreatedAt      time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
 }
 
 // 关系表
 
+// Notification 站内通知，在被回复、被提及、被邀请进入组织或提交被评测时发给用户
+type Notification struct {
+	ID        uint64    `gorm:"primary_key; autoIncrement; not null;" json:"id"`
+	UserID    uint64    `gorm:"not null; index:idx_notification_user;" json:"userID"` // 接收者ID
+	Type      int       `gorm:"not null;" json:"type"`                                // 1为回复，2为提及，3为组织邀请，4为评测结果
+	ActorID   uint64    `gorm:"not null;" json:"actorID"`                             // 触发通知的用户ID
+	TargetID  uint64    `gorm:"not null;" json:"targetID"`                            // 回复与提及为帖子ID，组织邀请为组织ID，评测结果为题目ID
+	SourceID  uint64    `gorm:"not null; default:0" json:"sourceID"`                  // 回复与提及为评论ID(帖子中的提及为0)，评测结果为评测记录ID
+	Content   string    `gorm:"size:256; not null;" json:"content"`                   // 通知的摘要
+	IsRead    bool      `gorm:"not null; default:false; index:idx_notification_user;" json:"isRead"`
+	CreatedAt time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
+}
+
 // Invitation 用户组织关系
 type Invitation struct {
 	ID        uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
@@ -117,6 +336,84 @@ type ContestProblem struct {
 	ID        uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
 	ContestID uint64 `gorm:"not null;" json:"contestID"`
 	ProblemID uint64 `gorm:"not null;" json:"problemID"`
+	Label     string `gorm:"size:8; not null;" json:"label"`      // 题目在比赛中的编号，如A、B、C
+	Position  int    `gorm:"not null; default:0" json:"position"` // 题目在比赛中的位置，从0开始
+	Score     int    `gorm:"not null; default:0" json:"score"`    // 题目在OI赛制下的分值
+	Title     string `gorm:"size:32;" json:"title"`               // 题目在比赛中显示的标题，为空则使用题目原名称
+}
+
+// VirtualContest 用户虚拟参赛关系
+type VirtualContest struct {
+	ID        uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID    uint64    `gorm:"not null; uniqueIndex:idx_virtual_contest;" json:"userID"`
+	ContestID uint64    `gorm:"not null; uniqueIndex:idx_virtual_contest;" json:"contestID"`
+	StartTime time.Time `gorm:"not null;" json:"startTime"` // 虚拟参赛的开始时间
+	EndTime   time.Time `gorm:"not null;" json:"endTime"`   // 虚拟参赛的结束时间，与原比赛时长相同
+}
+
+// ClarificationRead 用户比赛答疑已读关系
+type ClarificationRead struct {
+	ID         uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID     uint64    `gorm:"not null;" json:"userID"`
+	ContestID  uint64    `gorm:"not null;" json:"contestID"`
+	LastReadAt time.Time `gorm:"not null;" json:"lastReadAt"` // 用户最后一次读取该比赛答疑的时间
+}
+
+// RatingHistory 用户比赛积分变化关系
+type RatingHistory struct {
+	ID          uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID      uint64    `gorm:"not null; index;" json:"userID"`
+	ContestID   uint64    `gorm:"not null;" json:"contestID"`
+	Rank        int       `gorm:"not null;" json:"rank"`
+	OldRating   int       `gorm:"not null;" json:"oldRating"`
+	NewRating   int       `gorm:"not null;" json:"newRating"`
+	CreatedTime time.Time `gorm:"autoCreateTime;" json:"createdTime"`
+}
+
+// ContestTemplateProblem 比赛模板题目池关系
+type ContestTemplateProblem struct {
+	ID         uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	TemplateID uint64 `gorm:"not null;" json:"templateID"`
+	ProblemID  uint64 `gorm:"not null;" json:"problemID"`
+	Position   int    `gorm:"not null;" json:"position"`
+}
+
+// ExamSession 用户考试会话关系，考试期间每个用户只能有一个会话
+type ExamSession struct {
+	ID             uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	ContestID      uint64    `gorm:"not null;" json:"contestID"`
+	UserID         uint64    `gorm:"not null;" json:"userID"`
+	Key            string    `gorm:"size:64; not null;" json:"key"` // 会话密钥，考试期间的请求需在x-exam-session请求头中携带
+	IP             string    `gorm:"size:64;" json:"ip"`
+	LastActiveTime time.Time `json:"lastActiveTime"`
+}
+
+// ExamLog 考试期间被拦截的访问记录，供监考者查阅
+type ExamLog struct {
+	ID          uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	ContestID   uint64    `gorm:"not null; index;" json:"contestID"`
+	UserID      uint64    `gorm:"not null;" json:"userID"`
+	IP          string    `gorm:"size:64;" json:"ip"`
+	Path        string    `json:"path"`
+	Reason      string    `json:"reason"`
+	CreatedTime time.Time `gorm:"autoCreateTime;" json:"createdTime"`
+}
+
+// TutorialLessonProblem 教程课时题目关系，即课时中嵌入的练习题
+type TutorialLessonProblem struct {
+	ID        uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	LessonID  uint64 `gorm:"not null; index;" json:"lessonID"`
+	ProblemID uint64 `gorm:"not null;" json:"problemID"`
+	Position  int    `gorm:"not null;" json:"position"`
+}
+
+// LessonProgress 用户课时学习关系，用户学完课时内容后记录
+type LessonProgress struct {
+	ID            uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID        uint64    `gorm:"not null; index;" json:"userID"`
+	TutorialID    uint64    `gorm:"not null;" json:"tutorialID"`
+	LessonID      uint64    `gorm:"not null;" json:"lessonID"`
+	CompletedTime time.Time `gorm:"autoCreateTime;" json:"completedTime"`
 }
 
 // Result 用户问题关系
@@ -127,4 +424,49 @@ type Result struct {
 	Result      int       `gorm:"not null;" json:"result"` // 0 AC , 1 WA , 2 TLE, 3 RE
 	Language    string    `gorm:"not null;" json:"language"`
 	CreatedTime time.Time `gorm:"autoCreateTime;" json:"createdTime"`
+	VirtualID   uint64    `gorm:"default:0; index;" json:"virtualID"` // 提交所属的虚拟参赛记录ID，不在虚拟参赛时间窗口内提交时为0
+}
+
+// NotificationPreference 用户通知偏好关系，没有记录的通知类型默认接收
+type NotificationPreference struct {
+	ID      uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID  uint64 `gorm:"not null; uniqueIndex:idx_notification_preference;" json:"userID"`
+	Type    int    `gorm:"not null; uniqueIndex:idx_notification_preference;" json:"type"`
+	Enabled bool   `gorm:"not null;" json:"enabled"`
+}
+
+// Subscription 用户订阅关系，订阅帖子时接收新评论，订阅板块时接收新帖子
+type Subscription struct {
+	ID         uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID     uint64    `gorm:"not null; uniqueIndex:idx_subscription;" json:"userID"`
+	OrgID      uint64    `gorm:"not null; uniqueIndex:idx_subscription;" json:"orgID"`
+	TargetType int       `gorm:"not null; uniqueIndex:idx_subscription; index:idx_subscription_target;" json:"targetType"` // 1为帖子，2为板块
+	TargetID   uint64    `gorm:"not null; uniqueIndex:idx_subscription; index:idx_subscription_target;" json:"targetID"`   // 帖子ID或板块类型
+	CreatedAt  time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
+}
+
+// DigestSetting 用户摘要邮件设置关系，记录发送频率与已汇总到的帖子和评论
+type DigestSetting struct {
+	ID            uint64     `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID        uint64     `gorm:"not null; uniqueIndex;" json:"userID"`
+	Frequency     int        `gorm:"not null;" json:"frequency"`               // 1为即时，2为每日，3为每周
+	LastPostID    uint64     `gorm:"not null; default:0" json:"lastPostID"`    // 已汇总的最大帖子ID
+	LastCommentID uint64     `gorm:"not null; default:0" json:"lastCommentID"` // 已汇总的最大评论ID
+	LastSentAt    *time.Time `json:"lastSentAt"`                               // 上次汇总的时间，为空表示尚未汇总
+}
+
+// ConversationMember 用户会话关系，记录用户已读到的消息
+type ConversationMember struct {
+	ID                uint64 `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	ConversationID    uint64 `gorm:"not null; uniqueIndex:idx_conversation_member;" json:"conversationID"`
+	UserID            uint64 `gorm:"not null; uniqueIndex:idx_conversation_member; index;" json:"userID"`
+	LastReadMessageID uint64 `gorm:"not null; default:0" json:"lastReadMessageID"` // 已读的最大消息ID
+}
+
+// UserBlock 用户屏蔽关系，被屏蔽的用户无法与屏蔽者单聊或拉其进入群聊，其群聊消息对屏蔽者隐藏
+type UserBlock struct {
+	ID        uint64    `gorm:"primary_key; autoIncrement;not null;" json:"id"`
+	UserID    uint64    `gorm:"not null; uniqueIndex:idx_user_block;" json:"userID"`
+	BlockedID uint64    `gorm:"not null; uniqueIndex:idx_user_block;" json:"blockedID"`
+	CreatedAt time.Time `gorm:"autoCreateTime; not null;" json:"createdAt"`
 }
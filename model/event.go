@@ -0,0 +1,17 @@
+package model
+
+type VerdictEventT struct {
+	ProblemID uint64  `json:"problemID"`
+	Result    ResultT `json:"result"`
+}
+
+type ClarificationEventT struct {
+	ContestID       uint64 `json:"contestID"`
+	ClarificationID uint64 `json:"clarificationID"`
+	IsAnnouncement  bool   `json:"isAnnouncement"`
+}
+
+type ScoreboardEventT struct {
+	ContestID uint64 `json:"contestID"`
+	UserID    uint64 `json:"userID"` // 提交评测结果、导致榜单变化的用户ID
+}
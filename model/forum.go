@@ -6,17 +6,39 @@ type PostT struct {
 	CreatorName   string `json:"creatorName"`
 	CreatorAvatar string `json:"creatorAvatar"`
 	Title         string `json:"title"`
+	CommentCount  int    `json:"commentCount"`
+	Score         int    `json:"score"`
+	IsAnswered    bool   `json:"isAnswered"` // 是否已有被采纳的答案
+	IsPinned      bool   `json:"isPinned"`
+	IsLocked      bool   `json:"isLocked"`
+	IsSolution    bool   `json:"isSolution"` // 是否包含题解
 	UpdatedAt     string `json:"updatedAt"`
 }
 
+type ReactionT struct {
+	Emoji     string `json:"emoji"`
+	Count     int    `json:"count"`
+	IsReacted bool   `json:"isReacted"` // 当前用户是否做出了该回应
+}
+
 type CommentT struct {
-	ID            uint64 `json:"id"`
-	ToID          uint64 `json:"toID"`      // 被评论的评论ID，可为空
-	CreatorID     uint64 `json:"creatorID"` // 评论者ID
-	CreatorName   string `json:"creatorName"`
-	CreatorAvatar string `json:"creatorAvatar"`
-	Content       string `json:"content"`
-	UpdatedAt     string `json:"updatedAt"`
+	ID             uint64      `json:"id"`
+	ToID           uint64      `json:"toID"`      // 被评论的评论ID，可为空
+	CreatorID      uint64      `json:"creatorID"` // 评论者ID
+	CreatorName    string      `json:"creatorName"`
+	CreatorAvatar  string      `json:"creatorAvatar"`
+	Content        string      `json:"content"`     // Markdown源文本，用于编辑
+	ContentHTML    string      `json:"contentHTML"` // 渲染并过滤后的HTML，用于展示
+	UpdatedAt      string      `json:"updatedAt"`
+	Depth          int         `json:"depth"`      // 评论在评论树中的深度，顶层评论为0
+	ReplyCount     int         `json:"replyCount"` // 直接回复该评论的评论数
+	EditCount      int         `json:"editCount"`  // 被编辑的次数，大于0时显示已编辑标记
+	EditedAt       string      `json:"editedAt"`   // 最近一次编辑的时间，未被编辑时为空
+	Score          int         `json:"score"`
+	IsAccepted     bool        `json:"isAccepted"` // 是否被采纳为答案
+	Reactions      []ReactionT `json:"reactions"`
+	HasMoreReplies bool        `json:"hasMoreReplies"` // 是否有未加载的更深层回复，需通过获取回复接口加载
+	Replies        []CommentT  `json:"replies"`
 }
 
 type CreatePostQ struct {
@@ -26,6 +48,12 @@ type CreatePostQ struct {
 	Content string `json:"content"`
 }
 
+type CreateProblemPostQ struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	IsSolution bool   `json:"isSolution"` // 是否包含题解，包含题解的帖子仅对通过题目的用户可见
+}
+
 type UpdatePostQ struct {
 	Type    int    `json:"type"` // 帖子所属的板块，0为公告板块，1为划水板块，2为讨论板块
 	Title   string `json:"title"`
@@ -40,8 +68,20 @@ type GetPostA struct {
 	CreatorName   string `json:"creatorName"`
 	CreatorAvatar string `json:"creatorAvatar"`
 	Title         string `json:"title"`
-	Content       string `json:"content"`
-	UpdatedAt     string `json:"updatedAt"`
+	Content       string `json:"content"`     // Markdown源文本，用于编辑
+	ContentHTML   string `json:"contentHTML"` // 渲染并过滤后的HTML，用于展示
+	CommentCount  int    `json:"commentCount"`
+	Score         int    `json:"score"`
+	// 被采纳为答案的评论ID，为0表示没有采纳答案
+	AcceptedCommentID uint64      `json:"acceptedCommentID"`
+	Reactions         []ReactionT `json:"reactions"`
+	IsPinned          bool        `json:"isPinned"`
+	IsLocked          bool        `json:"isLocked"`  // 是否锁定，锁定后普通成员无法评论
+	EditCount         int         `json:"editCount"` // 被编辑的次数，大于0时显示已编辑标记
+	EditedAt          string      `json:"editedAt"`  // 最近一次编辑的时间，未被编辑时为空
+	ProblemID         uint64      `json:"problemID"` // 所属题目的ID，为0表示组织板块中的帖子
+	IsSolution        bool        `json:"isSolution"`
+	UpdatedAt         string      `json:"updatedAt"`
 }
 
 type GetAllPostA struct {
@@ -51,6 +91,15 @@ type GetAllPostA struct {
 	Posts   []PostT `json:"posts"`
 }
 
+type GetProblemPostA struct {
+	Success     bool    `json:"success"`
+	Message     string  `json:"message"`
+	Total       int     `json:"total"`
+	HiddenCount int     `json:"hiddenCount"` // 因未通过题目而被隐藏的题解帖子数
+	IsClosed    bool    `json:"isClosed"`    // 题目位于正在进行的比赛中，讨论已关闭
+	Posts       []PostT `json:"posts"`
+}
+
 type CreateCommentQ struct {
 	ToID    uint64 `json:"toID"` // 被评论的评论ID，可为空
 	Content string `json:"content"`
@@ -61,7 +110,107 @@ type UpdateCommentQ struct {
 }
 
 type GetCommentA struct {
-	Success  bool       `json:"success"`
-	Message  string     `json:"message"`
-	Comments []CommentT `json:"comments"`
+	Success      bool       `json:"success"`
+	Message      string     `json:"message"`
+	Total        int64      `json:"total"`        // 顶层评论总数
+	CommentCount int        `json:"commentCount"` // 评论总数，包括所有回复
+	Comments     []CommentT `json:"comments"`
+}
+
+type GetCommentReplyA struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message"`
+	Replies []CommentT `json:"replies"`
+}
+
+type ReactQ struct {
+	Emoji string `json:"emoji"` // 表情名称，点赞为+1
+}
+
+type ReactA struct {
+	Success   bool        `json:"success"`
+	Message   string      `json:"message"`
+	IsReacted bool        `json:"isReacted"` // 操作后当前用户是否做出了该回应
+	Reactions []ReactionT `json:"reactions"`
+}
+
+type AcceptAnswerQ struct {
+	CommentID uint64 `json:"commentID"` // 被采纳为答案的评论ID，为0表示取消采纳
+}
+
+type ModeratePostQ struct {
+	IsPinned *bool `json:"isPinned"` // 是否置顶，为空表示不修改
+	IsLocked *bool `json:"isLocked"` // 是否锁定，为空表示不修改
+	// 是否包含题解，为空表示不修改，仅对题目讨论中的帖子有效
+	IsSolution *bool `json:"isSolution"`
+	Type       *int  `json:"type"` // 移动到的板块，为空表示不修改
+}
+
+type CreateReportQ struct {
+	Reason string `json:"reason"`
+}
+
+type ReportT struct {
+	ID           uint64 `json:"id"`
+	TargetType   int    `json:"targetType"` // 举报对象的类型，1为帖子，2为评论
+	TargetID     uint64 `json:"targetID"`
+	PostID       uint64 `json:"postID"` // 举报对象所在的帖子ID
+	Content      string `json:"content"`
+	IsDeleted    bool   `json:"isDeleted"` // 举报对象是否已被删除
+	ReporterID   uint64 `json:"reporterID"`
+	ReporterName string `json:"reporterName"`
+	Reason       string `json:"reason"`
+	Status       int    `json:"status"` // 0为待处理，1为已处理，2为已驳回
+	HandlerID    uint64 `json:"handlerID"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+type GetReportA struct {
+	Success bool      `json:"success"`
+	Message string    `json:"message"`
+	Reports []ReportT `json:"reports"`
+}
+
+type HandleReportQ struct {
+	Status       int  `json:"status"`       // 1为已处理，2为已驳回
+	DeleteTarget bool `json:"deleteTarget"` // 处理时是否同时删除被举报的对象
+}
+
+type DeletedForumT struct {
+	ID            uint64 `json:"id"`
+	PostID        uint64 `json:"postID"`
+	Title         string `json:"title"` // 帖子标题，评论为空
+	Content       string `json:"content"`
+	CreatorID     uint64 `json:"creatorID"`
+	CreatorName   string `json:"creatorName"`
+	DeletedBy     uint64 `json:"deletedBy"`
+	DeletedByName string `json:"deletedByName"`
+	DeletedAt     string `json:"deletedAt"`
+}
+
+type GetDeletedForumA struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Items   []DeletedForumT `json:"items"`
+}
+
+type RevisionT struct {
+	Version    int    `json:"version"` // 版本号，从1开始，1为原始版本
+	EditorID   uint64 `json:"editorID"`
+	EditorName string `json:"editorName"`
+	Title      string `json:"title"` // 帖子标题，评论为空
+	Content    string `json:"content"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+type GetRevisionA struct {
+	Success   bool        `json:"success"`
+	Message   string      `json:"message"`
+	Revisions []RevisionT `json:"revisions"`
+}
+
+type GetRevisionDiffA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Diff    string `json:"diff"` // unified diff格式的差异文本
 }
@@ -0,0 +1,83 @@
+package model
+
+type MessageT struct {
+	ID             uint64 `json:"id"`
+	ConversationID uint64 `json:"conversationID"`
+	SenderID       uint64 `json:"senderID"`
+	SenderName     string `json:"senderName"`
+	SenderAvatar   string `json:"senderAvatar"`
+	Content        string `json:"content"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+type ConversationMemberT struct {
+	UserID uint64 `json:"userID"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+type ConversationT struct {
+	ID          uint64                `json:"id"`
+	IsGroup     bool                  `json:"isGroup"`
+	Title       string                `json:"title"` // 群聊的名称，单聊为空
+	Members     []ConversationMemberT `json:"members"`
+	LastMessage *MessageT             `json:"lastMessage"` // 最近一条消息，为空表示还没有消息
+	UnreadCount int64                 `json:"unreadCount"`
+}
+
+type GetConversationA struct {
+	Success       bool            `json:"success"`
+	Message       string          `json:"message"`
+	Conversations []ConversationT `json:"conversations"`
+}
+
+type CreateConversationQ struct {
+	UserIDs []uint64 `json:"userIDs"` // 除自己以外的会话成员，只有一人时为单聊
+	Title   string   `json:"title"`   // 群聊的名称，单聊时忽略
+}
+
+type CreateConversationA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	ID      uint64 `json:"id"`
+}
+
+type GetMessageA struct {
+	Success  bool       `json:"success"`
+	Message  string     `json:"message"`
+	Total    int64      `json:"total"`
+	Messages []MessageT `json:"messages"`
+}
+
+type SendMessageQ struct {
+	Content string `json:"content"`
+}
+
+type SendMessageA struct {
+	Success     bool     `json:"success"`
+	Message     string   `json:"message"`
+	SentMessage MessageT `json:"sentMessage"`
+}
+
+type GetUnreadMessageCountA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Count   int64  `json:"count"`
+}
+
+type BlockT struct {
+	UserID    uint64 `json:"userID"`
+	Name      string `json:"name"`
+	Avatar    string `json:"avatar"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type GetBlockA struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message"`
+	Blocks  []BlockT `json:"blocks"`
+}
+
+type CreateBlockQ struct {
+	UserID uint64 `json:"userID"`
+}
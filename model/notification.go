@@ -0,0 +1,84 @@
+package model
+
+type NotificationT struct {
+	ID          uint64 `json:"id"`
+	Type        int    `json:"type"` // 1为回复，2为提及，3为组织邀请，4为评测结果
+	ActorID     uint64 `json:"actorID"`
+	ActorName   string `json:"actorName"`
+	ActorAvatar string `json:"actorAvatar"`
+	TargetID    uint64 `json:"targetID"` // 回复与提及为帖子ID，组织邀请为组织ID，评测结果为题目ID
+	SourceID    uint64 `json:"sourceID"` // 回复与提及为评论ID(帖子中的提及为0)，评测结果为评测记录ID
+	Content     string `json:"content"`
+	IsRead      bool   `json:"isRead"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type NotificationPreferenceT struct {
+	Type    int  `json:"type"`
+	Enabled bool `json:"enabled"`
+}
+
+type GetNotificationA struct {
+	Success       bool            `json:"success"`
+	Message       string          `json:"message"`
+	Total         int64           `json:"total"`
+	Notifications []NotificationT `json:"notifications"`
+}
+
+type GetUnreadNotificationCountA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Count   int64  `json:"count"`
+}
+
+type ReadNotificationQ struct {
+	IDs []uint64 `json:"ids"` // 要标记为已读的通知ID，为空表示全部标记为已读
+}
+
+type GetNotificationPreferenceA struct {
+	Success     bool                      `json:"success"`
+	Message     string                    `json:"message"`
+	Preferences []NotificationPreferenceT `json:"preferences"`
+}
+
+type UpdateNotificationPreferenceQ struct {
+	Preferences []NotificationPreferenceT `json:"preferences"`
+}
+
+type SubscriptionT struct {
+	ID         uint64 `json:"id"`
+	OrgID      uint64 `json:"orgID"`
+	TargetType int    `json:"targetType"` // 1为帖子，2为板块
+	TargetID   uint64 `json:"targetID"`   // 帖子ID或板块类型
+	TargetName string `json:"targetName"` // 帖子标题或板块名称
+	CreatedAt  string `json:"createdAt"`
+}
+
+type GetSubscriptionA struct {
+	Success       bool            `json:"success"`
+	Message       string          `json:"message"`
+	Subscriptions []SubscriptionT `json:"subscriptions"`
+}
+
+type CreateSubscriptionQ struct {
+	OrgID      uint64 `json:"orgID"`      // 订阅板块时的组织ID，订阅帖子时可不填
+	TargetType int    `json:"targetType"` // 1为帖子，2为板块
+	TargetID   uint64 `json:"targetID"`   // 帖子ID或板块类型
+}
+
+type CreateSubscriptionA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	ID      uint64 `json:"id"`
+}
+
+type GetDigestSettingA struct {
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	Frequency  int    `json:"frequency"`  // 1为即时，2为每日，3为每周
+	LastSentAt string `json:"lastSentAt"` // 上次发送摘要的时间，为空表示尚未发送
+}
+
+type UpdateDigestSettingQ struct {
+	Frequency int `json:"frequency"` // 1为即时，2为每日，3为每周
+}
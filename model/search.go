@@ -0,0 +1,18 @@
+package model
+
+type SearchHitT struct {
+	Type       int     `json:"type"`       // 结果类型，1为题目，2为教程，3为帖子，4为教程课时
+	ID         uint64  `json:"id"`         // 对应的题目、教程、帖子或课时ID
+	TutorialID uint64  `json:"tutorialID"` // 课时所属的教程ID，仅课时结果有效
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"` // 内容中与关键字相关的片段
+	Score      float64 `json:"score"`   // 相关度，越大越相关
+}
+
+type SearchA struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Total   int          `json:"total"`   // 结果总数，hasMore为true时为已统计的结果数
+	HasMore bool         `json:"hasMore"` // 结果总数是否达到统计上限，之后可能还有更多结果
+	Hits    []SearchHitT `json:"hits"`
+}
@@ -15,26 +15,33 @@ type CreateTutorialQ struct {
 	Profile  string                `form:"profile"`
 	Readable int                   `form:"readable"`
 	Writable int                   `form:"writable"`
+	MainFile string                `form:"mainFile"` // 上传zip教程包时主文档的相对路径，为空则自动查找index.md、README.md或根目录下唯一的markdown文件
 	File     *multipart.FileHeader `form:"file" swaggerignore:"true"`
 }
 
 type GetTutorialA struct {
-	Success      bool   `json:"success"`
-	Message      string `json:"message"`
-	OrgID        uint64 `json:"orgID"`
-	CreatorID    uint64 `json:"creatorID"`
-	CreatorName  string `json:"creatorName"`
-	Name         string `json:"name"`
-	Profile      string `json:"profile"`
-	Version      int    `json:"version"`
-	TutorialPath string `json:"tutorialPath"` // 教程的下载路径
+	Success      bool               `json:"success"`
+	Message      string             `json:"message"`
+	OrgID        uint64             `json:"orgID"`
+	CreatorID    uint64             `json:"creatorID"`
+	CreatorName  string             `json:"creatorName"`
+	Name         string             `json:"name"`
+	Profile      string             `json:"profile"`
+	Version      int                `json:"version"`
+	TutorialPath string             `json:"tutorialPath"` // 教程的下载路径
+	BundlePath   string             `json:"bundlePath"`   // 教程包的下载路径，教程为单个文件时为空
+	Chapters     []TutorialChapterT `json:"chapters"`     // 教程的章节与课时目录
+	Lock         TutorialLockT      `json:"lock"`         // 教程的编辑锁
 }
 
 type UpdateTutorialQ struct {
-	ID      uint64                `form:"id"`
-	Name    string                `form:"name"`
-	Profile string                `form:"profile"`
-	File    *multipart.FileHeader `form:"file" swaggerignore:"true"`
+	ID          uint64                `form:"id"`
+	Name        string                `form:"name"`
+	Profile     string                `form:"profile"`
+	Note        string                `form:"note"`        // 本次修改的说明，至多255个字符
+	BaseVersion int                   `form:"baseVersion"` // 本次修改所基于的教程版本，需与当前版本一致
+	MainFile    string                `form:"mainFile"`    // 上传zip教程包时主文档的相对路径
+	File        *multipart.FileHeader `form:"file" swaggerignore:"true"`
 }
 
 type GetTutorialVersionA struct {
@@ -49,3 +56,181 @@ type GetTutorialListA struct {
 	Total        int         `json:"total"`
 	TutorialList []TutorialT `json:"tutorialList"`
 }
+
+type TutorialLessonT struct {
+	ID       uint64 `json:"id"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+	Version  int    `json:"version"`
+}
+
+type TutorialChapterT struct {
+	ID       uint64            `json:"id"`
+	Title    string            `json:"title"`
+	Position int               `json:"position"`
+	Lessons  []TutorialLessonT `json:"lessons"`
+}
+
+type LessonProblemT struct {
+	ID         uint64 `json:"id"`
+	Name       string `json:"name"`
+	Difficulty int    `json:"difficulty"`
+}
+
+type CreateTutorialChapterQ struct {
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+type UpdateTutorialChapterQ struct {
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
+
+type CreateTutorialLessonQ struct {
+	ChapterID  uint64                `form:"chapterID"`
+	Title      string                `form:"title"`
+	Position   int                   `form:"position"`
+	ProblemIDs []uint64              `form:"problemIDs"` // 课时中嵌入的练习题ID列表
+	File       *multipart.FileHeader `form:"file" swaggerignore:"true"`
+}
+
+type UpdateTutorialLessonQ struct {
+	ChapterID  uint64                `form:"chapterID"`
+	Title      string                `form:"title"`
+	Position   int                   `form:"position"`
+	ProblemIDs []uint64              `form:"problemIDs"`
+	File       *multipart.FileHeader `form:"file" swaggerignore:"true"` // 为空表示不更新课时内容
+}
+
+type GetTutorialLessonA struct {
+	Success    bool             `json:"success"`
+	Message    string           `json:"message"`
+	ID         uint64           `json:"id"`
+	TutorialID uint64           `json:"tutorialID"`
+	ChapterID  uint64           `json:"chapterID"`
+	Title      string           `json:"title"`
+	Position   int              `json:"position"`
+	Version    int              `json:"version"`
+	LessonPath string           `json:"lessonPath"` // 课时内容的下载路径
+	Problems   []LessonProblemT `json:"problems"`   // 课时中嵌入的、当前用户可读的练习题
+}
+
+type LessonProgressT struct {
+	LessonID  uint64 `json:"lessonID"`
+	ChapterID uint64 `json:"chapterID"`
+	Title     string `json:"title"`
+	IsRead    bool   `json:"isRead"`    // 是否已学完课时内容
+	Solved    int    `json:"solved"`    // 已通过的练习题数量
+	Total     int    `json:"total"`     // 练习题总数
+	Completed bool   `json:"completed"` // 课时内容已学完，且所有练习题均已通过
+}
+
+type TutorialProgressT struct {
+	CompletedLessons int `json:"completedLessons"`
+	TotalLessons     int `json:"totalLessons"`
+	SolvedProblems   int `json:"solvedProblems"`
+	TotalProblems    int `json:"totalProblems"`
+}
+
+type MemberProgressT struct {
+	UserID   uint64            `json:"userID"`
+	UserName string            `json:"userName"`
+	Progress TutorialProgressT `json:"progress"`
+}
+
+type GetTutorialProgressA struct {
+	Success  bool              `json:"success"`
+	Message  string            `json:"message"`
+	Progress TutorialProgressT `json:"progress"`
+	Lessons  []LessonProgressT `json:"lessons"`
+}
+
+type GetMemberProgressA struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Members []MemberProgressT `json:"members"`
+}
+
+type TutorialVersionT struct {
+	Version      int    `json:"version"`
+	AuthorID     uint64 `json:"authorID"`
+	AuthorName   string `json:"authorName"`
+	Note         string `json:"note"`
+	CreatedTime  string `json:"createdTime"`
+	TutorialPath string `json:"tutorialPath"` // 该版本教程的下载路径
+	BundlePath   string `json:"bundlePath"`   // 该版本教程包的下载路径，该版本为单个文件时为空
+}
+
+type GetTutorialVersionListA struct {
+	Success  bool               `json:"success"`
+	Message  string             `json:"message"`
+	Versions []TutorialVersionT `json:"versions"`
+}
+
+type GetTutorialDiffA struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Diff    string `json:"diff"` // unified diff格式的差异文本
+}
+
+type UpdateTutorialA struct {
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	IsConflict bool   `json:"isConflict"` // 是否因教程已被他人更新而失败
+	Version    int    `json:"version"`    // 教程的当前版本
+}
+
+type RestoreTutorialVersionQ struct {
+	Note        string `json:"note"`        // 修改说明，至多255个字符，为空时使用默认的修改说明
+	BaseVersion int    `json:"baseVersion"` // 本次恢复所基于的教程版本，需与当前版本一致
+}
+
+type TutorialLockT struct {
+	IsLocked   bool   `json:"isLocked"`
+	LockerID   uint64 `json:"lockerID"`
+	LockerName string `json:"lockerName"`
+	ExpireTime string `json:"expireTime"`
+}
+
+type LockTutorialA struct {
+	Success bool          `json:"success"`
+	Message string        `json:"message"`
+	Lock    TutorialLockT `json:"lock"` // 操作后教程的编辑锁状态
+}
+
+type TutorialCommentT struct {
+	ID              uint64             `json:"id"`
+	ParentID        uint64             `json:"parentID"`
+	CreatorID       uint64             `json:"creatorID"`
+	CreatorName     string             `json:"creatorName"`
+	CreatorAvatar   string             `json:"creatorAvatar"`
+	Content         string             `json:"content"`
+	Version         int                `json:"version"`
+	AnchorHeading   string             `json:"anchorHeading"`
+	AnchorStartLine int                `json:"anchorStartLine"`
+	AnchorEndLine   int                `json:"anchorEndLine"`
+	IsOutdated      bool               `json:"isOutdated"` // 锚定部分在之后的版本中是否已被修改
+	CreatedAt       string             `json:"createdAt"`
+	Replies         []TutorialCommentT `json:"replies"`
+}
+
+type CreateTutorialCommentQ struct {
+	ParentID        uint64 `json:"parentID"` // 被回复的评论ID，为0表示顶层评论，回复不能锚定
+	Content         string `json:"content"`
+	Version         int    `json:"version"`         // 锚定的教程版本，为0表示当前版本
+	AnchorHeading   string `json:"anchorHeading"`   // 锚定的标题，如"## 快速排序"，为空表示不锚定到标题
+	AnchorStartLine int    `json:"anchorStartLine"` // 锚定的行范围，从1开始，为0表示不锚定到行范围
+	AnchorEndLine   int    `json:"anchorEndLine"`
+}
+
+type UpdateTutorialCommentQ struct {
+	Content string `json:"content"`
+}
+
+type GetTutorialCommentA struct {
+	Success     bool               `json:"success"`
+	Message     string             `json:"message"`
+	CanModerate bool               `json:"canModerate"` // 当前用户是否可以管理所有评论，即是否具有教程的可写权限
+	Comments    []TutorialCommentT `json:"comments"`
+}
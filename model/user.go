@@ -36,13 +36,24 @@ type CreateTokenA struct {
 	ID      uint64 `json:"id"`
 }
 
+type RatingT struct {
+	ContestID   uint64 `json:"contestID"`
+	ContestName string `json:"contestName"`
+	Rank        int    `json:"rank"`
+	OldRating   int    `json:"oldRating"`
+	NewRating   int    `json:"newRating"`
+	CreatedTime string `json:"createdTime"`
+}
+
 type GetUserA struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Name    string `json:"name"`
-	Email   string `json:"email"`
-	Profile string `json:"profile"`
-	Avatar  string `json:"avatar"`
+	Success       bool      `json:"success"`
+	Message       string    `json:"message"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Profile       string    `json:"profile"`
+	Avatar        string    `json:"avatar"`
+	Rating        int       `json:"rating"`
+	RatingHistory []RatingT `json:"ratingHistory"` // 用户的积分变化历史，按时间升序排列，用于绘制积分曲线
 }
 
 type GetUserOrganizationA struct {
@@ -1,11 +1,40 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"github.com/phoenix-next/phoenix-server/global"
 	"github.com/phoenix-next/phoenix-server/model"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+	"unicode/utf8"
 )
 
+// 比赛题目的编号与显示标题的最大字符数，与数据库字段长度一致
+const (
+	ContestProblemLabelMaxLength = 8
+	ContestProblemTitleMaxLength = 32
+)
+
+// Helper
+
+// JudgeContestReadPermission 判别用户对比赛的可读权限，2为所有人可读，1为组织成员可读，0为组织管理员可读
+func JudgeContestReadPermission(uid uint64, contest model.Contest) bool {
+	if contest.Readable == 2 {
+		return true
+	}
+	invitation, notFound := GetInvitationByUserOrg(uid, contest.OrgID)
+	switch contest.Readable {
+	case 1:
+		return !notFound
+	case 0:
+		return !notFound && invitation.IsAdmin
+	default:
+		return false
+	}
+}
+
 // GetReadableContest 获取用户可读的比赛，并按照指定的sorter排序
 func GetReadableContest(userID uint64, sorter int) (contests []model.ContestT) {
 	adminOrg := []uint64{0}
@@ -32,3 +61,333 @@ func GetReadableContest(userID uint64, sorter int) (contests []model.ContestT) {
 		Find(&contests)
 	return
 }
+
+// CalculateStanding 根据按时间升序排列的评测记录，计算单个用户在比赛中的成绩
+// 每道题以首次通过为准，首次通过前的每次错误提交罚时20分钟
+func CalculateStanding(problemIDs []uint64, results []model.Result, start time.Time) (standing model.StandingT) {
+	problems := make(map[uint64]*model.StandingProblemT)
+	standing.Problems = make([]model.StandingProblemT, len(problemIDs))
+	for i, id := range problemIDs {
+		standing.Problems[i].ProblemID = id
+		problems[id] = &standing.Problems[i]
+	}
+	for _, result := range results {
+		problem, ok := problems[result.ProblemID]
+		if !ok || problem.Accepted {
+			continue
+		}
+		if result.Result != 0 {
+			problem.Tries++
+			continue
+		}
+		problem.Accepted = true
+		problem.AcceptedTime = int(result.CreatedTime.Sub(start).Minutes())
+		standing.Solved++
+		standing.Penalty += problem.AcceptedTime + problem.Tries*20
+	}
+	return
+}
+
+// RankStandings 按通过题数降序、罚时升序对榜单排序，并计算名次，成绩相同者名次相同
+func RankStandings(standings []model.StandingT) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Solved != standings[j].Solved {
+			return standings[i].Solved > standings[j].Solved
+		}
+		return standings[i].Penalty < standings[j].Penalty
+	})
+	for i := range standings {
+		if i > 0 && standings[i].Solved == standings[i-1].Solved && standings[i].Penalty == standings[i-1].Penalty {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+}
+
+// GetContestStandings 获取比赛开始elapsed时长后的榜单，仅统计比赛时间内对比赛题目的提交
+func GetContestStandings(contest model.Contest, elapsed time.Duration) (standings []model.StandingT) {
+	standings = make([]model.StandingT, 0)
+	problemIDs := GetContestProblemIDs(contest.ID)
+	if duration := contest.EndTime.Sub(contest.StartTime); elapsed > duration {
+		elapsed = duration
+	}
+	// 按用户对评测记录进行分组
+	userIDs := make([]uint64, 0)
+	userResults := make(map[uint64][]model.Result)
+	for _, result := range QueryProblemResults(problemIDs, contest.StartTime, contest.StartTime.Add(elapsed)) {
+		if _, ok := userResults[result.UserID]; !ok {
+			userIDs = append(userIDs, result.UserID)
+		}
+		userResults[result.UserID] = append(userResults[result.UserID], result)
+	}
+	// 计算每个用户的成绩
+	users := GetUsersByIDs(userIDs)
+	for _, uid := range userIDs {
+		standing := CalculateStanding(problemIDs, userResults[uid], contest.StartTime)
+		standing.UserID, standing.UserName = uid, users[uid].Name
+		standings = append(standings, standing)
+	}
+	RankStandings(standings)
+	return
+}
+
+// GetVirtualStanding 获取用户虚拟参赛至elapsed时长时的成绩，仅统计虚拟参赛时间窗口内且标记为该次虚拟参赛的提交
+func GetVirtualStanding(virtual model.VirtualContest, problemIDs []uint64, elapsed time.Duration) (standing model.StandingT) {
+	end := virtual.StartTime.Add(elapsed)
+	if end.After(virtual.EndTime) {
+		end = virtual.EndTime
+	}
+	results := make([]model.Result, 0)
+	for _, result := range QueryProblemResults(problemIDs, virtual.StartTime, end) {
+		if result.UserID == virtual.UserID && result.VirtualID == virtual.ID {
+			results = append(results, result)
+		}
+	}
+	user, _ := GetUserByID(virtual.UserID)
+	standing = CalculateStanding(problemIDs, results, virtual.StartTime)
+	standing.UserID, standing.UserName, standing.IsVirtual = user.ID, user.Name, true
+	return
+}
+
+// HasUserJoinedContest 判断用户是否在比赛时间内提交过比赛中的题目，即是否正式参加过比赛
+func HasUserJoinedContest(uid uint64, contest model.Contest) bool {
+	var count int64
+	global.DB.Model(&model.Result{}).
+		Where("user_id = ? AND problem_id IN ? AND created_time BETWEEN ? AND ?",
+			uid, GetContestProblemIDs(contest.ID), contest.StartTime, contest.EndTime).
+		Count(&count)
+	return count > 0
+}
+
+// GetContestProblemLabel 根据题目在比赛中的位置生成编号，依次为A、B、...、Z、AA、AB等
+func GetContestProblemLabel(position int) string {
+	label := ""
+	for position++; position > 0; position = (position - 1) / 26 {
+		label = string(rune('A'+(position-1)%26)) + label
+	}
+	return label
+}
+
+// BuildContestProblems 根据请求构建比赛题目关系，题目的位置即为其在请求中的顺序
+// 若problems为空，则使用problemIDs构建；若有题目不存在或重复出现，编号重复或过长，标题过长，则ok为false
+func BuildContestProblems(problemIDs []uint64, problems []model.ContestProblemQ) (res []model.ContestProblem, ok bool) {
+	if len(problems) == 0 {
+		for _, id := range problemIDs {
+			problems = append(problems, model.ContestProblemQ{ProblemID: id})
+		}
+	}
+	res = make([]model.ContestProblem, 0)
+	visited, labels := make(map[uint64]bool), make(map[string]bool)
+	for i, problem := range problems {
+		if _, notFound := GetProblemByID(problem.ProblemID); notFound || visited[problem.ProblemID] {
+			return res, false
+		}
+		visited[problem.ProblemID] = true
+		label := problem.Label
+		if label == "" {
+			label = GetContestProblemLabel(i)
+		}
+		// 编号与标题的长度不能超过数据库字段长度，同一比赛中的编号不能重复
+		if utf8.RuneCountInString(label) > ContestProblemLabelMaxLength || labels[label] ||
+			utf8.RuneCountInString(problem.Title) > ContestProblemTitleMaxLength {
+			return res, false
+		}
+		labels[label] = true
+		res = append(res, model.ContestProblem{
+			ProblemID: problem.ProblemID,
+			Label:     label,
+			Position:  i,
+			Score:     problem.Score,
+			Title:     problem.Title})
+	}
+	return res, true
+}
+
+// IsContestRunning 判断比赛当前是否正在进行
+func IsContestRunning(contest model.Contest) bool {
+	now := time.Now()
+	return !now.Before(contest.StartTime) && now.Before(contest.EndTime)
+}
+
+// 数据库操作
+
+// GetContestByID 根据比赛 ID 查询某个比赛
+func GetContestByID(ID uint64) (contest model.Contest, notFound bool) {
+	err := global.DB.First(&contest, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return contest, true
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.LOG.Panic("GetContestByID: search error")
+		return contest, true
+	} else {
+		return contest, false
+	}
+}
+
+// GetContestProblems 获取比赛中所有的题目关系，按题目位置排列
+func GetContestProblems(contestID uint64) (problems []model.ContestProblem) {
+	problems = make([]model.ContestProblem, 0)
+	global.DB.Where("contest_id = ?", contestID).Order("position asc, id asc").Find(&problems)
+	return
+}
+
+// GetContestProblemIDs 获取比赛中所有题目的ID，按题目位置排列
+func GetContestProblemIDs(contestID uint64) (problemIDs []uint64) {
+	problemIDs = make([]uint64, 0)
+	for _, problem := range GetContestProblems(contestID) {
+		problemIDs = append(problemIDs, problem.ProblemID)
+	}
+	return
+}
+
+// CreateContest 在一个事务中创建比赛及其题目关系
+func CreateContest(contest *model.Contest, problems []model.ContestProblem) error {
+	if contest.IsExam {
+		defer InvalidateExamCache()
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		return createContest(tx, contest, problems)
+	})
+}
+
+// createContest 在给定事务中创建比赛及其题目关系
+func createContest(tx *gorm.DB, contest *model.Contest, problems []model.ContestProblem) error {
+	if err := tx.Create(contest).Error; err != nil {
+		return err
+	}
+	for i := range problems {
+		problems[i].ContestID = contest.ID
+		if err := tx.Create(&problems[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateContest 在一个事务中更新比赛及其题目关系
+// 仍在比赛中的题目原地更新编号、位置、分值与标题，新增的题目插入，被移除的题目删除
+func UpdateContest(contest *model.Contest, problems []model.ContestProblem) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		// 只更新可修改的字段，避免覆盖并发写入的结算状态等字段
+		if err := tx.Model(contest).Select("name", "profile", "is_rated").Updates(contest).Error; err != nil {
+			return err
+		}
+		// 获取比赛原有的题目关系
+		origins := make([]model.ContestProblem, 0)
+		if err := tx.Where("contest_id = ?", contest.ID).Find(&origins).Error; err != nil {
+			return err
+		}
+		originMap := make(map[uint64]model.ContestProblem)
+		for _, origin := range origins {
+			originMap[origin.ProblemID] = origin
+		}
+		// 更新或插入题目关系
+		for _, problem := range problems {
+			problem.ContestID = contest.ID
+			if origin, ok := originMap[problem.ProblemID]; ok {
+				problem.ID = origin.ID
+				delete(originMap, problem.ProblemID)
+			}
+			if err := tx.Save(&problem).Error; err != nil {
+				return err
+			}
+		}
+		// 删除被移除的题目关系
+		for _, origin := range originMap {
+			if err := tx.Delete(&origin).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// QueryProblemResults 查询一段时间内对若干题目的所有评测结果，按时间升序排列
+func QueryProblemResults(problemIDs []uint64, start time.Time, end time.Time) (results []model.Result) {
+	results = make([]model.Result, 0)
+	if len(problemIDs) == 0 {
+		return
+	}
+	global.DB.Where("problem_id IN ? AND created_time BETWEEN ? AND ?", problemIDs, start, end).
+		Order("created_time asc").Find(&results)
+	return
+}
+
+// GetVirtualContest 获取用户在某比赛中的虚拟参赛记录
+func GetVirtualContest(uid uint64, contestID uint64) (virtual model.VirtualContest, notFound bool) {
+	err := global.DB.Where("user_id = ? AND contest_id = ?", uid, contestID).First(&virtual).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return virtual, true
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.LOG.Panic("GetVirtualContest: search error")
+		return virtual, true
+	} else {
+		return virtual, false
+	}
+}
+
+// GetActiveVirtualContest 获取用户当前正在进行且包含该题目的虚拟参赛记录
+func GetActiveVirtualContest(uid uint64, problemID uint64) (virtual model.VirtualContest, notFound bool) {
+	now := time.Now()
+	err := global.DB.Where("user_id = ? AND start_time <= ? AND end_time >= ?", uid, now, now).
+		Where("contest_id IN (?)", global.DB.Model(&model.ContestProblem{}).Select("contest_id").Where("problem_id = ?", problemID)).
+		First(&virtual).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return virtual, true
+	} else if err != nil {
+		global.LOG.Panic("GetActiveVirtualContest: search error")
+		return virtual, true
+	}
+	return virtual, false
+}
+
+// CreateVirtualContest 创建虚拟参赛记录
+func CreateVirtualContest(virtual *model.VirtualContest) (err error) {
+	if err = global.DB.Create(virtual).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetClarificationByID 根据ID获取比赛答疑
+func GetClarificationByID(ID uint64) (clarification model.Clarification, notFound bool) {
+	err := global.DB.First(&clarification, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return clarification, true
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.LOG.Panic("GetClarificationByID: search error")
+		return clarification, true
+	} else {
+		return clarification, false
+	}
+}
+
+// GetVisibleClarifications 获取用户可见的、在since之后更新的比赛答疑
+// 管理员可见所有答疑，参赛者仅可见公开的答疑以及自己的提问
+func GetVisibleClarifications(uid uint64, contestID uint64, isAdmin bool, since time.Time) (clarifications []model.Clarification) {
+	clarifications = make([]model.Clarification, 0)
+	db := global.DB.Where("contest_id = ? AND updated_at > ?", contestID, since)
+	if !isAdmin {
+		db = db.Where("is_public = ? OR creator_id = ?", true, uid)
+	}
+	db.Order("updated_at desc").Find(&clarifications)
+	return
+}
+
+// GetClarificationReadTime 获取用户最后一次读取某比赛答疑的时间
+func GetClarificationReadTime(uid uint64, contestID uint64) time.Time {
+	var read model.ClarificationRead
+	if err := global.DB.Where("user_id = ? AND contest_id = ?", uid, contestID).First(&read).Error; err != nil {
+		return time.Time{}
+	}
+	return read.LastReadAt
+}
+
+// UpdateClarificationReadTime 更新用户最后一次读取某比赛答疑的时间
+func UpdateClarificationReadTime(uid uint64, contestID uint64, readAt time.Time) {
+	var read model.ClarificationRead
+	global.DB.Where(model.ClarificationRead{UserID: uid, ContestID: contestID}).FirstOrInit(&read)
+	read.LastReadAt = readAt
+	global.DB.Save(&read)
+}
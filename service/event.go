@@ -0,0 +1,204 @@
+package service
+
+import (
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 推送事件的类型
+const (
+	EventTypeVerdict       = "verdict"
+	EventTypeNotification  = "notification"
+	EventTypeClarification = "clarification"
+	EventTypeScoreboard    = "scoreboard"
+	EventTypeMessage       = "message"
+	EventTypeReset         = "reset" // 无法从客户端给出的事件ID续传，客户端应重新拉取数据
+)
+
+// 推送通道相关的参数
+const (
+	eventHistorySize = 1024 // 保留的最近事件数，用于断线续传
+	eventBufferSize  = 64   // 每个订阅者的缓冲区大小，缓冲区满时断开该订阅者，由客户端重连续传
+)
+
+// Event 推送事件
+type Event struct {
+	ID        string // 事件ID，由服务器启动标识与序号组成，服务器重启后旧的事件ID无法续传
+	Seq       uint64
+	Type      string
+	UserID    uint64 // 事件所属的用户，比赛无关的事件仅推送给该用户
+	ContestID uint64 // 比赛相关事件的比赛ID，为0表示与比赛无关
+	IsPublic  bool   // 比赛相关事件是否推送给所有可读该比赛的用户，否则仅推送给所属用户与组织管理员
+	Data      interface{}
+}
+
+// eventHub 进程内的事件分发中心
+type eventHub struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	history     []Event
+	subscribers map[chan Event]bool
+}
+
+var hub = &eventHub{
+	epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+	history:     make([]Event, 0, eventHistorySize),
+	subscribers: make(map[chan Event]bool),
+}
+
+// Helper
+
+// PublishEvent 发布一个事件，不会阻塞发布者
+func PublishEvent(event Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.seq++
+	event.Seq = hub.seq
+	event.ID = hub.epoch + "-" + strconv.FormatUint(event.Seq, 10)
+	if len(hub.history) == eventHistorySize {
+		hub.history = append(hub.history[:0], hub.history[1:]...)
+	}
+	hub.history = append(hub.history, event)
+	for ch := range hub.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(hub.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// SubscribeEvents 订阅事件，并返回lastEventID之后的历史事件，complete为false表示无法完整续传
+func SubscribeEvents(lastEventID string) (ch chan Event, backlog []Event, complete bool) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	ch = make(chan Event, eventBufferSize)
+	hub.subscribers[ch] = true
+	backlog = make([]Event, 0)
+	if lastEventID == "" {
+		return ch, backlog, true
+	}
+	parts := strings.SplitN(lastEventID, "-", 2)
+	if len(parts) != 2 || parts[0] != hub.epoch {
+		return ch, backlog, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq > hub.seq {
+		return ch, backlog, false
+	}
+	complete = len(hub.history) == 0 || hub.history[0].Seq <= seq+1
+	for _, event := range hub.history {
+		if event.Seq > seq {
+			backlog = append(backlog, event)
+		}
+	}
+	return ch, backlog, complete
+}
+
+// UnsubscribeEvents 取消订阅
+func UnsubscribeEvents(ch chan Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.subscribers[ch] {
+		delete(hub.subscribers, ch)
+		close(ch)
+	}
+}
+
+// EventFilter 判断事件是否应推送给某个用户，缓存比赛权限，需定期刷新
+type EventFilter struct {
+	userID   uint64
+	exams    map[uint64]bool // 用户正在参加的考试，考试期间只推送评测结果与考试相关的事件
+	readable map[uint64]bool
+	admin    map[uint64]bool
+}
+
+// NewEventFilter 新建用户的事件过滤器
+func NewEventFilter(userID uint64) *EventFilter {
+	filter := &EventFilter{userID: userID}
+	filter.Refresh()
+	return filter
+}
+
+// Refresh 清空权限缓存，重新获取用户正在参加的考试
+func (f *EventFilter) Refresh() {
+	f.exams = make(map[uint64]bool)
+	f.readable = make(map[uint64]bool)
+	f.admin = make(map[uint64]bool)
+	for _, contest := range GetRunningExams(f.userID) {
+		f.exams[contest.ID] = true
+	}
+}
+
+// Allow 判断事件是否应推送给该用户
+func (f *EventFilter) Allow(event Event) bool {
+	if len(f.exams) > 0 && (event.Type == EventTypeNotification || event.Type == EventTypeMessage ||
+		(event.ContestID != 0 && !f.exams[event.ContestID])) {
+		return false
+	}
+	if event.UserID == f.userID {
+		return true
+	}
+	if event.ContestID == 0 {
+		return false
+	}
+	if _, ok := f.admin[event.ContestID]; !ok {
+		contest, notFound := GetContestByID(event.ContestID)
+		f.admin[event.ContestID] = !notFound && IsOrganizationAdmin(f.userID, contest.OrgID)
+		f.readable[event.ContestID] = !notFound && JudgeContestReadPermission(f.userID, contest)
+	}
+	return f.admin[event.ContestID] || (event.IsPublic && f.readable[event.ContestID])
+}
+
+// PublishVerdictEvent 推送评测结果，若题目属于正在进行的比赛，同时推送榜单变化
+func PublishVerdictEvent(result model.Result) {
+	PublishEvent(Event{
+		Type:   EventTypeVerdict,
+		UserID: result.UserID,
+		Data: model.VerdictEventT{
+			ProblemID: result.ProblemID,
+			Result: model.ResultT{
+				ID:          result.ID,
+				Result:      result.Result,
+				CreatedTime: result.CreatedTime.Format("2006-01-02 15:04:05"),
+				Language:    result.Language,
+				Path:        "resource/code/" + GetCodeFileName(result)}}})
+	for _, contest := range GetRunningContestsByProblem(result.ProblemID) {
+		PublishEvent(Event{
+			Type:      EventTypeScoreboard,
+			UserID:    result.UserID,
+			ContestID: contest.ID,
+			IsPublic:  true,
+			Data:      model.ScoreboardEventT{ContestID: contest.ID, UserID: result.UserID}})
+	}
+}
+
+// PublishClarificationEvent 推送比赛答疑的新增或回答，非公开的答疑仅推送给提问者与组织管理员
+func PublishClarificationEvent(clarification model.Clarification) {
+	PublishEvent(Event{
+		Type:      EventTypeClarification,
+		UserID:    clarification.CreatorID,
+		ContestID: clarification.ContestID,
+		IsPublic:  clarification.IsPublic,
+		Data: model.ClarificationEventT{
+			ContestID:       clarification.ContestID,
+			ClarificationID: clarification.ID,
+			IsAnnouncement:  clarification.IsAnnouncement}})
+}
+
+// 数据库操作
+
+// GetRunningContestsByProblem 获取包含该题目的、正在进行的比赛
+func GetRunningContestsByProblem(problemID uint64) (contests []model.Contest) {
+	now := time.Now()
+	global.DB.Where("id IN (?) AND start_time <= ? AND end_time > ?",
+		global.DB.Model(&model.ContestProblem{}).Select("contest_id").Where("problem_id = ?", problemID), now, now).
+		Find(&contests)
+	return
+}
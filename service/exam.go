@@ -0,0 +1,134 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// 考试相关的参数
+const (
+	ExamSessionTimeout   = 5 * time.Minute  // 考试会话的超时时间，超时未活动的会话可被其他设备取代
+	ExamSessionHeartbeat = 30 * time.Second // 考试会话活动时间的最小更新间隔，避免每个请求都写数据库
+	examCacheDuration    = time.Minute      // 未结束考试列表的缓存时长
+)
+
+// examCache 缓存所有未结束的考试，没有考试时中间件无需查询用户的组织
+type examCache struct {
+	mu       sync.Mutex
+	contests []model.Contest
+	loadedAt time.Time
+}
+
+var exams = &examCache{}
+
+// Helper
+
+// GenerateExamSessionKey 生成一个随机的考试会话密钥
+func GenerateExamSessionKey() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		global.LOG.Panic("GenerateExamSessionKey: generate key error")
+	}
+	return hex.EncodeToString(b)
+}
+
+// IsExamParticipant 判断用户是否为考试的考生，即考试所属组织中的非管理员成员
+func IsExamParticipant(uid uint64, contest model.Contest) bool {
+	invitation, notFound := GetInvitationByUserOrg(uid, contest.OrgID)
+	return contest.IsExam && !notFound && !invitation.IsAdmin
+}
+
+// ShouldRefreshExamSession 判断是否需要更新考试会话的活动时间与IP
+func ShouldRefreshExamSession(session model.ExamSession, ip string) bool {
+	return session.IP != ip || time.Since(session.LastActiveTime) >= ExamSessionHeartbeat
+}
+
+// InvalidateExamCache 比赛被创建或删除后使缓存失效，下次访问时重新加载
+func InvalidateExamCache() {
+	exams.mu.Lock()
+	defer exams.mu.Unlock()
+	exams.contests = nil
+}
+
+// getUnfinishedExams 获取所有未结束的考试，缓存过期或失效时重新加载
+func getUnfinishedExams() []model.Contest {
+	exams.mu.Lock()
+	defer exams.mu.Unlock()
+	if exams.contests == nil || time.Since(exams.loadedAt) >= examCacheDuration {
+		contests := make([]model.Contest, 0)
+		global.DB.Where("is_exam = ? AND end_time > ?", true, time.Now()).Find(&contests)
+		exams.contests, exams.loadedAt = contests, time.Now()
+	}
+	return exams.contests
+}
+
+// IsExamSessionActive 判断考试会话是否仍处于活动状态
+func IsExamSessionActive(session model.ExamSession) bool {
+	return time.Since(session.LastActiveTime) < ExamSessionTimeout
+}
+
+// 数据库操作
+
+// GetRunningExams 获取用户作为考生正在参加的所有考试，当前没有正在进行的考试时不查询数据库
+func GetRunningExams(uid uint64) (contests []model.Contest) {
+	contests = make([]model.Contest, 0)
+	now := time.Now()
+	running := make([]model.Contest, 0)
+	for _, contest := range getUnfinishedExams() {
+		if !now.Before(contest.StartTime) && now.Before(contest.EndTime) {
+			running = append(running, contest)
+		}
+	}
+	if len(running) == 0 {
+		return
+	}
+	orgIDs := make(map[uint64]bool)
+	for _, invitation := range GetUserOrganization(uid) {
+		if !invitation.IsAdmin {
+			orgIDs[invitation.OrgID] = true
+		}
+	}
+	for _, contest := range running {
+		if orgIDs[contest.OrgID] {
+			contests = append(contests, contest)
+		}
+	}
+	return
+}
+
+// GetExamSession 获取用户在某考试中的会话
+func GetExamSession(contestID uint64, uid uint64) (session model.ExamSession, notFound bool) {
+	err := global.DB.Where("contest_id = ? AND user_id = ?", contestID, uid).First(&session).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return session, true
+	} else if err != nil {
+		global.LOG.Panic("GetExamSession: search error")
+		return session, true
+	}
+	return session, false
+}
+
+// SaveExamSession 保存考试会话
+func SaveExamSession(session *model.ExamSession) (err error) {
+	err = global.DB.Save(session).Error
+	return err
+}
+
+// CreateExamLog 记录一次考试期间被拦截的访问，并写入日志
+func CreateExamLog(contestID uint64, uid uint64, ip string, path string, reason string) {
+	global.LOG.Warn("exam ", contestID, " blocked user ", uid, " from ", ip, " at ", path, ": ", reason)
+	global.DB.Create(&model.ExamLog{ContestID: contestID, UserID: uid, IP: ip, Path: path, Reason: reason})
+}
+
+// GetExamLogs 获取考试期间被拦截的所有访问记录，按时间降序排列
+func GetExamLogs(contestID uint64) (logs []model.ExamLog) {
+	logs = make([]model.ExamLog, 0)
+	global.DB.Where("contest_id = ?", contestID).Order("created_time desc").Find(&logs)
+	return
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Helper
+
+// GetContestExportFileName 获取比赛归档对应的文件名称
+func GetContestExportFileName(export model.ContestExport) string {
+	return "contest_" + strconv.FormatUint(export.ContestID, 10) + "_" + strconv.FormatUint(export.ID, 10) + ".zip"
+}
+
+// GetContestExportFromParam 通过路径参数获取比赛归档任务，仅比赛所属组织的管理员可以获取
+func GetContestExportFromParam(c *gin.Context) (export model.ContestExport, ok bool) {
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	eid, err2 := strconv.ParseUint(c.Param("exportID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		global.LOG.Warn("GetContestExportFromParam: id invalid")
+		return export, false
+	}
+	export, notFound := GetContestExportByID(eid)
+	if notFound || export.ContestID != id {
+		global.LOG.Warn("GetContestExportFromParam: contest export not found")
+		return export, false
+	}
+	contest, notFound := GetContestByID(id)
+	if notFound || !IsOrganizationAdmin(utils.SolveUser(c).ID, contest.OrgID) {
+		return export, false
+	}
+	return export, true
+}
+
+// RunContestExport 生成比赛归档并更新归档任务的状态，该函数耗时较长，应在后台运行
+func RunContestExport(export model.ContestExport, contest model.Contest) {
+	status := 2
+	path := filepath.Join(global.VP.GetString("export_path"), GetContestExportFileName(export))
+	// 后台任务中的panic不能被gin捕获，需要在此处恢复，并将任务标记为失败
+	defer func() {
+		if err := recover(); err != nil {
+			global.LOG.Warn("RunContestExport: ", err)
+		}
+		if status != 1 {
+			_ = os.Remove(path)
+		}
+		global.DB.Model(&export).Updates(map[string]interface{}{"status": status, "finished_time": time.Now()})
+	}()
+	if err := writeContestArchive(path, contest); err != nil {
+		global.LOG.Warn("RunContestExport: ", err)
+		return
+	}
+	status = 1
+}
+
+// writeContestArchive 将比赛的最终榜单、所有提交的元数据与代码、比赛使用的题目文件写入zip归档
+func writeContestArchive(path string, contest model.Contest) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	defer func() {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	problems := GetContestProblems(contest.ID)
+	// 写入最终榜单
+	standings := GetContestStandings(contest, contest.EndTime.Sub(contest.StartTime))
+	data, err := json.MarshalIndent(standings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = utils.ZipBytes(w, data, "standings.json"); err != nil {
+		return err
+	}
+	if err = writeStandingsCSV(w, problems, standings); err != nil {
+		return err
+	}
+	// 写入题目文件
+	labels := make(map[uint64]string)
+	problemIDs := make([]uint64, 0)
+	exportProblems := make([]model.ExportProblemT, 0)
+	for _, item := range problems {
+		labels[item.ProblemID] = item.Label
+		problemIDs = append(problemIDs, item.ProblemID)
+		problem, notFound := GetProblemByID(item.ProblemID)
+		if notFound {
+			continue
+		}
+		// 使用比赛结束时的题目版本，而不是题目当前的版本
+		version := GetProblemVersionAt(problem, contest.EndTime)
+		folder := "problems/" + item.Label
+		for _, kind := range []string{"description", "input", "output"} {
+			src := filepath.Join(global.VP.GetString("problem_path"), GetProblemFileFolder(problem.ID, version), kind)
+			if utils.ZipFile(w, src, folder+"/"+kind) != nil {
+				global.LOG.Warn("writeContestArchive: problem file " + src + " missing")
+			}
+		}
+		exportProblems = append(exportProblems, model.ExportProblemT{
+			ProblemID: problem.ID,
+			Label:     item.Label,
+			Title:     item.Title,
+			Name:      problem.Name,
+			Version:   version,
+			Score:     item.Score,
+			Folder:    folder})
+	}
+	if data, err = json.MarshalIndent(exportProblems, "", "  "); err != nil {
+		return err
+	}
+	if err = utils.ZipBytes(w, data, "problems.json"); err != nil {
+		return err
+	}
+	// 写入比赛提交的元数据与代码
+	results := QueryProblemResults(problemIDs, contest.StartTime, contest.EndTime)
+	userIDs := make([]uint64, 0)
+	for _, result := range results {
+		userIDs = append(userIDs, result.UserID)
+	}
+	users := GetUsersByIDs(userIDs)
+	submissions := make([]model.ExportSubmissionT, 0)
+	for _, result := range results {
+		codeFile := "code/" + GetCodeFileName(result)
+		src := filepath.Join(global.VP.GetString("code_path"), GetCodeFileName(result))
+		if utils.ZipFile(w, src, codeFile) != nil {
+			global.LOG.Warn("writeContestArchive: code file " + GetCodeFileName(result) + " missing")
+			codeFile = ""
+		}
+		submissions = append(submissions, model.ExportSubmissionT{
+			ID:          result.ID,
+			UserID:      result.UserID,
+			UserName:    users[result.UserID].Name,
+			ProblemID:   result.ProblemID,
+			Label:       labels[result.ProblemID],
+			Result:      result.Result,
+			Language:    result.Language,
+			CodeFile:    codeFile,
+			CreatedTime: result.CreatedTime.Format("2006-01-02 15:04:05")})
+	}
+	if data, err = json.MarshalIndent(submissions, "", "  "); err != nil {
+		return err
+	}
+	return utils.ZipBytes(w, data, "submissions.json")
+}
+
+// writeStandingsCSV 将榜单以CSV格式写入zip归档，每道题目一列，格式为"+错误次数(通过时刻)"或"-错误次数"
+func writeStandingsCSV(w *zip.Writer, problems []model.ContestProblem, standings []model.StandingT) error {
+	f, err := w.Create("standings.csv")
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(f)
+	header := []string{"rank", "userID", "userName", "solved", "penalty"}
+	for _, problem := range problems {
+		header = append(header, problem.Label)
+	}
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+	for _, standing := range standings {
+		record := []string{
+			strconv.Itoa(standing.Rank),
+			strconv.FormatUint(standing.UserID, 10),
+			standing.UserName,
+			strconv.Itoa(standing.Solved),
+			strconv.Itoa(standing.Penalty)}
+		for _, problem := range standing.Problems {
+			switch {
+			case problem.Accepted:
+				record = append(record, fmt.Sprintf("+%d(%d)", problem.Tries, problem.AcceptedTime))
+			case problem.Tries > 0:
+				record = append(record, fmt.Sprintf("-%d", problem.Tries))
+			default:
+				record = append(record, "")
+			}
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// 数据库操作
+
+// GetContestExportByID 根据ID获取比赛归档任务
+func GetContestExportByID(ID uint64) (export model.ContestExport, notFound bool) {
+	err := global.DB.First(&export, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return export, true
+	} else if err != nil {
+		global.LOG.Panic("GetContestExportByID: search error")
+		return export, true
+	}
+	return export, false
+}
+
+// ResumeContestExports 重新运行服务器重启前未完成的归档任务，比赛已被删除的任务标记为失败
+func ResumeContestExports() {
+	exports := make([]model.ContestExport, 0)
+	global.DB.Where("status = ?", 0).Find(&exports)
+	for _, export := range exports {
+		contest, notFound := GetContestByID(export.ContestID)
+		if notFound {
+			global.DB.Model(&export).Updates(map[string]interface{}{"status": 2, "finished_time": time.Now()})
+			continue
+		}
+		go RunContestExport(export, contest)
+	}
+}
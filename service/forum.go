@@ -1,10 +1,70 @@
 package service
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/phoenix-next/phoenix-server/global"
 	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strconv"
+	"time"
+)
+
+// PostTypeDiscussion 讨论板块，该板块的帖子可以采纳答案
+const PostTypeDiscussion = 2
+
+// 帖子列表的排序方式
+const (
+	PostSortUpdated    = 0 // 按更新时间降序
+	PostSortScore      = 1 // 按点赞数降序
+	PostSortActivity   = 2 // 按最近活跃时间降序
+	PostSortUnanswered = 3 // 仅包含未采纳答案的帖子，按更新时间降序
+)
+
+// IsValidPostSorter 判断帖子列表的排序方式是否合法
+func IsValidPostSorter(sorter int) bool {
+	return sorter >= PostSortUpdated && sorter <= PostSortUnanswered
+}
+
+// ForumAuditPeriod 被删除的帖子与评论的保留期，期间管理员可以恢复，期满后永久删除
+const ForumAuditPeriod = 30 * 24 * time.Hour
+
+// 举报的处理状态
+const (
+	ReportStatusPending   = 0
+	ReportStatusResolved  = 1
+	ReportStatusDismissed = 2
+)
+
+// 表情回应与举报的对象类型
+const (
+	ForumTargetPost    = 1
+	ForumTargetComment = 2
+)
+
+// UpvoteEmoji 点赞对应的表情，点赞数计入帖子与评论的得分
+const UpvoteEmoji = "+1"
+
+// allowedEmojis 允许使用的表情
+var allowedEmojis = map[string]bool{
+	UpvoteEmoji: true, "heart": true, "laugh": true, "hooray": true, "confused": true, "eyes": true,
+}
+
+// PostPageSize 板块与题目讨论中每页的帖子数
+const PostPageSize = 5
+
+// 帖子与评论内容的最大字符数
+const (
+	PostContentMaxLength    = 20000
+	CommentContentMaxLength = 5000
+)
+
+// 评论树分页相关的参数
+const (
+	CommentPageSize    = 10 // 每页的顶层评论数
+	CommentInlineDepth = 3  // 随顶层评论一并返回的回复层数，更深的回复需单独加载
 )
 
 // Helper
@@ -36,9 +96,208 @@ func GetCommentFromParam(c *gin.Context) (comment model.Comment, ok bool) {
 		global.LOG.Warn("GetCommentFromParam: comment not found")
 		return comment, false
 	}
+	// 帖子被删除后，其中的评论同样不可访问
+	if _, notFound = GetPostByID(comment.PostID); notFound {
+		global.LOG.Warn("GetCommentFromParam: post of comment not found")
+		return comment, false
+	}
 	return comment, true
 }
 
+// IsAllowedEmoji 判断表情是否允许使用
+func IsAllowedEmoji(emoji string) bool {
+	return allowedEmojis[emoji]
+}
+
+// BuildCommentTree 将帖子下的评论组织为以parentID为根的树形结构，深度超过maxDepth的回复不展开，批量获取评论者与表情回应
+func BuildCommentTree(post model.Post, comments []model.Comment, parentID uint64, maxDepth int, userID uint64) []model.CommentT {
+	userIDs, commentIDs := make([]uint64, 0), make([]uint64, 0)
+	children := make(map[uint64][]model.Comment)
+	for _, comment := range comments {
+		userIDs = append(userIDs, comment.CreatorID)
+		commentIDs = append(commentIDs, comment.ID)
+		children[comment.ToID] = append(children[comment.ToID], comment)
+	}
+	users := GetUsersByIDs(userIDs)
+	reactions := GetReactionSummaries(ForumTargetComment, commentIDs, userID)
+	var build func(parentID uint64) []model.CommentT
+	build = func(parentID uint64) []model.CommentT {
+		nodes := make([]model.CommentT, 0)
+		for _, comment := range children[parentID] {
+			node := model.CommentT{
+				ID:            comment.ID,
+				ToID:          comment.ToID,
+				CreatorID:     comment.CreatorID,
+				CreatorName:   users[comment.CreatorID].Name,
+				CreatorAvatar: users[comment.CreatorID].Avatar,
+				Content:       comment.Content,
+				ContentHTML:   GetContentHTML(comment.Content, comment.ContentHTML),
+				EditCount:     comment.EditCount,
+				EditedAt:      FormatEditedAt(comment.EditedAt),
+				UpdatedAt:     comment.UpdatedAt.Format("01-02 15:04"),
+				Depth:         comment.Depth,
+				ReplyCount:    comment.ReplyCount,
+				Score:         comment.Score,
+				IsAccepted:    comment.ID == post.AcceptedCommentID,
+				Reactions:     reactions[comment.ID],
+				Replies:       make([]model.CommentT, 0)}
+			if comment.Depth < maxDepth {
+				node.Replies = build(comment.ID)
+			} else {
+				node.HasMoreReplies = comment.ReplyCount > 0
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(parentID)
+}
+
+// BuildReports 批量获取举报者与被举报对象，生成审核队列，被删除的对象同样返回
+func BuildReports(reports []model.Report) []model.ReportT {
+	userIDs, postIDs, commentIDs := make([]uint64, 0), make([]uint64, 0), make([]uint64, 0)
+	for _, report := range reports {
+		userIDs = append(userIDs, report.ReporterID)
+		if report.TargetType == ForumTargetPost {
+			postIDs = append(postIDs, report.TargetID)
+		} else {
+			commentIDs = append(commentIDs, report.TargetID)
+		}
+	}
+	users := GetUsersByIDs(userIDs)
+	posts, comments := make(map[uint64]model.Post), make(map[uint64]model.Comment)
+	tmpPosts, tmpComments := make([]model.Post, 0), make([]model.Comment, 0)
+	global.DB.Unscoped().Where("id IN ?", append(postIDs, 0)).Find(&tmpPosts)
+	global.DB.Unscoped().Where("id IN ?", append(commentIDs, 0)).Find(&tmpComments)
+	for _, post := range tmpPosts {
+		posts[post.ID] = post
+	}
+	for _, comment := range tmpComments {
+		comments[comment.ID] = comment
+	}
+	res := make([]model.ReportT, 0)
+	for _, report := range reports {
+		item := model.ReportT{
+			ID:           report.ID,
+			TargetType:   report.TargetType,
+			TargetID:     report.TargetID,
+			ReporterID:   report.ReporterID,
+			ReporterName: users[report.ReporterID].Name,
+			Reason:       report.Reason,
+			Status:       report.Status,
+			HandlerID:    report.HandlerID,
+			CreatedAt:    report.CreatedAt.Format("2006-01-02 15:04:05")}
+		if report.TargetType == ForumTargetPost {
+			post := posts[report.TargetID]
+			item.PostID, item.Content, item.IsDeleted = post.ID, post.Title+"\n"+post.Content, post.DeletedAt.Valid
+		} else {
+			comment := comments[report.TargetID]
+			item.PostID, item.Content, item.IsDeleted = comment.PostID, comment.Content, comment.DeletedAt.Valid
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// BuildDeletedPosts 批量获取创建者与删除者，生成被删除帖子的列表
+func BuildDeletedPosts(posts []model.Post) []model.DeletedForumT {
+	userIDs := make([]uint64, 0)
+	for _, post := range posts {
+		userIDs = append(userIDs, post.CreatorID, post.DeletedBy)
+	}
+	users := GetUsersByIDs(userIDs)
+	res := make([]model.DeletedForumT, 0)
+	for _, post := range posts {
+		res = append(res, model.DeletedForumT{
+			ID:            post.ID,
+			PostID:        post.ID,
+			Title:         post.Title,
+			Content:       post.Content,
+			CreatorID:     post.CreatorID,
+			CreatorName:   users[post.CreatorID].Name,
+			DeletedBy:     post.DeletedBy,
+			DeletedByName: users[post.DeletedBy].Name,
+			DeletedAt:     post.DeletedAt.Time.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// BuildDeletedComments 批量获取创建者与删除者，生成被删除评论的列表
+func BuildDeletedComments(comments []model.Comment) []model.DeletedForumT {
+	userIDs := make([]uint64, 0)
+	for _, comment := range comments {
+		userIDs = append(userIDs, comment.CreatorID, comment.DeletedBy)
+	}
+	users := GetUsersByIDs(userIDs)
+	res := make([]model.DeletedForumT, 0)
+	for _, comment := range comments {
+		res = append(res, model.DeletedForumT{
+			ID:            comment.ID,
+			PostID:        comment.PostID,
+			Content:       comment.Content,
+			CreatorID:     comment.CreatorID,
+			CreatorName:   users[comment.CreatorID].Name,
+			DeletedBy:     comment.DeletedBy,
+			DeletedByName: users[comment.DeletedBy].Name,
+			DeletedAt:     comment.DeletedAt.Time.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// BuildRevisions 批量获取编辑者，生成修订版本列表
+func BuildRevisions(revisions []model.ForumRevision) []model.RevisionT {
+	editorIDs := make([]uint64, 0)
+	for _, revision := range revisions {
+		editorIDs = append(editorIDs, revision.EditorID)
+	}
+	editors := GetUsersByIDs(editorIDs)
+	res := make([]model.RevisionT, 0)
+	for _, revision := range revisions {
+		res = append(res, model.RevisionT{
+			Version:    revision.Version,
+			EditorID:   revision.EditorID,
+			EditorName: editors[revision.EditorID].Name,
+			Title:      revision.Title,
+			Content:    revision.Content,
+			CreatedAt:  revision.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// DiffRevisions 比较两个修订版本，帖子的标题作为第一行参与比较
+func DiffRevisions(revisions []model.ForumRevision, from int, to int) (diff string, ok bool) {
+	texts := make(map[int]string)
+	for _, revision := range revisions {
+		texts[revision.Version] = revision.Content
+		if revision.TargetType == ForumTargetPost {
+			texts[revision.Version] = revision.Title + "\n\n" + revision.Content
+		}
+	}
+	fromText, ok1 := texts[from]
+	toText, ok2 := texts[to]
+	if !ok1 || !ok2 {
+		return "", false
+	}
+	diff, err := utils.DiffText(fromText, toText, "v"+strconv.Itoa(from), "v"+strconv.Itoa(to), 3)
+	return diff, err == nil
+}
+
+// FormatEditedAt 格式化最近一次编辑的时间，未被编辑时为空
+func FormatEditedAt(editedAt *time.Time) string {
+	if editedAt == nil {
+		return ""
+	}
+	return editedAt.Format("01-02 15:04")
+}
+
+// GetContentHTML 获取内容渲染后的HTML，优先使用缓存，缓存为空时（如旧数据）重新渲染
+func GetContentHTML(content string, contentHTML string) string {
+	if contentHTML == "" && content != "" {
+		return utils.RenderMarkdown(content)
+	}
+	return contentHTML
+}
+
 // 数据库操作
 
 // GetPostByID 通过ID获取帖子
@@ -49,9 +308,151 @@ func GetPostByID(id uint64) (post model.Post, notFound bool) {
 	return post, false
 }
 
-// GetAllPosts 已知组织ID和帖子板块，获取所有帖子
-func GetAllPosts(oid uint64, postType int) (posts []model.Post) {
-	global.DB.Where("org_id = ? AND type = ?", oid, postType).Order("updated_at desc").Find(&posts)
+// GetAllPosts 已知组织ID和帖子板块，按给定的排序方式获取所有帖子，不包括题目讨论中的帖子
+func GetAllPosts(oid uint64, postType int, sorter int) (posts []model.Post) {
+	sortPosts(global.DB.Where("org_id = ? AND type = ? AND problem_id = 0", oid, postType), sorter).Find(&posts)
+	return
+}
+
+// GetProblemPosts 按给定的排序方式获取题目讨论中的所有帖子
+func GetProblemPosts(problemID uint64, sorter int) (posts []model.Post) {
+	sortPosts(global.DB.Where("problem_id = ?", problemID), sorter).Find(&posts)
+	return
+}
+
+// sortPosts 按给定的排序方式排列帖子，置顶帖子总是排在最前
+func sortPosts(db *gorm.DB, sorter int) *gorm.DB {
+	db = db.Order("is_pinned desc")
+	switch sorter {
+	case PostSortScore:
+		db = db.Order("score desc").Order("updated_at desc")
+	case PostSortActivity:
+		db = db.Order("COALESCE(last_activity_time, updated_at) desc")
+	case PostSortUnanswered:
+		db = db.Where("accepted_comment_id = 0").Order("updated_at desc")
+	default:
+		db = db.Order("updated_at desc")
+	}
+	return db
+}
+
+// BuildPosts 获取帖子创建者的名称和头像，生成帖子列表
+func BuildPosts(posts []model.Post) []model.PostT {
+	creatorIDs := make([]uint64, 0)
+	for _, post := range posts {
+		creatorIDs = append(creatorIDs, post.CreatorID)
+	}
+	creators := GetUsersByIDs(creatorIDs)
+	res := make([]model.PostT, 0)
+	for _, post := range posts {
+		res = append(res, model.PostT{
+			ID:            post.ID,
+			CreatorID:     post.CreatorID,
+			UpdatedAt:     post.UpdatedAt.Format("01-02 15:04"),
+			Title:         post.Title,
+			CommentCount:  post.CommentCount,
+			Score:         post.Score,
+			IsAnswered:    post.AcceptedCommentID != 0,
+			IsPinned:      post.IsPinned,
+			IsLocked:      post.IsLocked,
+			IsSolution:    post.IsSolution,
+			CreatorAvatar: creators[post.CreatorID].Avatar,
+			CreatorName:   creators[post.CreatorID].Name})
+	}
+	return res
+}
+
+// IsDiscussionClosed 判断题目讨论是否关闭，题目位于正在进行的比赛中时，仅题目创建者与组织管理员可以参与讨论
+func IsDiscussionClosed(problem model.Problem, uid uint64) bool {
+	if problem.Creator == uid || IsOrganizationAdmin(uid, problem.OrgID) {
+		return false
+	}
+	if len(GetRunningContestsByProblem(problem.ID)) > 0 {
+		return true
+	}
+	// 用户正在虚拟参加包含该题目的比赛时，讨论同样对其关闭
+	_, notFound := GetActiveVirtualContest(uid, problem.ID)
+	return !notFound
+}
+
+// CanViewSolution 判断用户能否查看题目的题解，已通过题目的用户、题目创建者与组织管理员可以查看
+func CanViewSolution(problem model.Problem, uid uint64) bool {
+	return problem.Creator == uid || IsOrganizationAdmin(uid, problem.OrgID) || GetUserFinalJudge(uid, problem.ID) == 1
+}
+
+// JudgePostReadPermission 判断用户能否查看并参与帖子的讨论：组织板块中的帖子要求用户是组织成员；
+// 题目讨论中的帖子要求用户可读题目且讨论未关闭，包含题解的帖子还要求用户能查看题解
+func JudgePostReadPermission(post model.Post, c *gin.Context) bool {
+	user := utils.SolveUser(c)
+	if post.ProblemID == 0 {
+		ok, err := IsUserInThisOrganization(user.ID, post.OrgID)
+		return ok && err == nil
+	}
+	problem, notFound := GetProblemByID(post.ProblemID)
+	if notFound || !JudgeProblemReadPermission(problem, c) || IsDiscussionClosed(problem, user.ID) {
+		return false
+	}
+	return !post.IsSolution || post.CreatorID == user.ID || CanViewSolution(problem, user.ID)
+}
+
+// AcceptAnswer 将帖子下的一条评论采纳为答案，commentID为0表示取消采纳
+func AcceptAnswer(post model.Post, commentID uint64) error {
+	return global.DB.Model(&post).UpdateColumn("accepted_comment_id", commentID).Error
+}
+
+// GetReactionSummaries 批量统计若干对象的表情回应，并标记当前用户做出的回应
+func GetReactionSummaries(targetType int, targetIDs []uint64, userID uint64) map[uint64][]model.ReactionT {
+	summaries := make(map[uint64][]model.ReactionT)
+	for _, id := range targetIDs {
+		summaries[id] = make([]model.ReactionT, 0)
+	}
+	if len(targetIDs) == 0 {
+		return summaries
+	}
+	rows := make([]struct {
+		TargetID  uint64
+		Emoji     string
+		Count     int
+		IsReacted bool
+	}, 0)
+	global.DB.Model(&model.Reaction{}).
+		Select("target_id, emoji, COUNT(*) AS count, MAX(user_id = ?) AS is_reacted", userID).
+		Where("target_type = ? AND target_id IN ?", targetType, targetIDs).
+		Group("target_id, emoji").Order("MIN(id)").Scan(&rows)
+	for _, row := range rows {
+		summaries[row.TargetID] = append(summaries[row.TargetID], model.ReactionT{Emoji: row.Emoji, Count: row.Count, IsReacted: row.IsReacted})
+	}
+	return summaries
+}
+
+// ToggleReaction 切换用户对帖子或评论的表情回应，已回应则撤销，否则新增，点赞同时更新对象的得分
+func ToggleReaction(userID uint64, targetType int, targetID uint64, emoji string) (isReacted bool, err error) {
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		delta := 1
+		reaction := model.Reaction{UserID: userID, TargetType: targetType, TargetID: targetID, Emoji: emoji}
+		result := tx.Where(&reaction).Delete(&model.Reaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			delta = -1
+		} else if err := tx.Create(&reaction).Error; utils.IsDuplicateKeyError(err) {
+			// 并发的相同请求已经做出了回应，视为已回应，不再重复计分
+			isReacted = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		isReacted = delta > 0
+		if emoji != UpvoteEmoji {
+			return nil
+		}
+		var target interface{} = &model.Post{}
+		if targetType == ForumTargetComment {
+			target = &model.Comment{}
+		}
+		return tx.Model(target).Where("id = ?", targetID).UpdateColumn("score", gorm.Expr("score + ?", delta)).Error
+	})
 	return
 }
 
@@ -63,8 +464,372 @@ func GetCommentByID(id uint64) (comment model.Comment, notFound bool) {
 	return comment, false
 }
 
-// GetAllCommentByPostID 获取一个帖子下的所有评论
-func GetAllCommentByPostID(postID uint64) (comments []model.Comment) {
-	global.DB.Where("post_id = ?", postID).Order("updated_at desc").Find(&comments)
+// GetTopCommentsByPage 分页获取一个帖子下的顶层评论，按发表顺序排列
+func GetTopCommentsByPage(postID uint64, page int) (comments []model.Comment, total int64) {
+	db := global.DB.Model(&model.Comment{}).Where("post_id = ? AND to_id = 0", postID)
+	db.Count(&total)
+	db.Order("id").Offset((page - 1) * CommentPageSize).Limit(CommentPageSize).Find(&comments)
+	return
+}
+
+// GetRepliesByRootIDs 获取若干评论树中深度在(minDepth, maxDepth]范围内的回复
+func GetRepliesByRootIDs(rootIDs []uint64, minDepth int, maxDepth int) (comments []model.Comment) {
+	if len(rootIDs) == 0 {
+		return
+	}
+	global.DB.Where("root_id IN ? AND depth > ? AND depth <= ?", rootIDs, minDepth, maxDepth).Order("id").Find(&comments)
+	return
+}
+
+// CreateComment 新建评论，并更新帖子的评论总数与被回复评论的回复数
+func CreateComment(comment *model.Comment, parent *model.Comment) error {
+	if parent != nil {
+		comment.ToID = parent.ID
+		comment.RootID = parent.RootID
+		if parent.RootID == 0 {
+			comment.RootID = parent.ID
+		}
+		comment.Depth = parent.Depth + 1
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Post{}).Where("id = ?", comment.PostID).UpdateColumns(map[string]interface{}{
+			"comment_count": gorm.Expr("comment_count + 1"), "last_activity_time": time.Now()}).Error; err != nil {
+			return err
+		}
+		if parent == nil {
+			return nil
+		}
+		return tx.Model(&model.Comment{}).Where("id = ?", parent.ID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+}
+
+// DeleteComment 软删除评论及其所有回复，并更新帖子的评论总数与被回复评论的回复数
+func DeleteComment(comment model.Comment, uid uint64) error {
+	rootID := comment.RootID
+	if rootID == 0 {
+		rootID = comment.ID
+	}
+	tree := make([]model.Comment, 0)
+	global.DB.Where("root_id = ?", rootID).Find(&tree)
+	children := make(map[uint64][]uint64)
+	for _, item := range tree {
+		children[item.ToID] = append(children[item.ToID], item.ID)
+	}
+	ids := []uint64{comment.ID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		// 同一次删除的评论具有相同的删除时间，恢复时据此找回整棵子树
+		if err := tx.Model(&model.Comment{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"deleted_by": uid, "deleted_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count - ?", len(ids))).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Post{}).Where("id = ? AND accepted_comment_id IN ?", comment.PostID, ids).
+			UpdateColumn("accepted_comment_id", 0).Error; err != nil {
+			return err
+		}
+		if comment.ToID == 0 {
+			return nil
+		}
+		return tx.Model(&model.Comment{}).Where("id = ? AND reply_count > 0", comment.ToID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count - 1")).Error
+	})
+}
+
+// RepairCommentTree 为旧版本遗留的回复补全所在评论树与深度，并重新统计评论数，由定时任务在服务器启动时调用
+// 这是一次性的迁移：只有存在未补全评论树的旧回复时才会执行，并在一个事务中完成，执行后不再有旧回复
+func RepairCommentTree() {
+	var count int64
+	global.DB.Unscoped().Model(&model.Comment{}).Where("to_id <> 0 AND root_id = 0").Count(&count)
+	if count == 0 {
+		return
+	}
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		legacy := make([]model.Comment, 0)
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("to_id <> 0 AND root_id = 0").Find(&legacy).Error; err != nil {
+			return err
+		}
+		for _, comment := range legacy {
+			// 沿回复关系向上查找顶层评论，父评论已被永久删除的回复视为顶层评论
+			rootID, depth, current := uint64(0), 0, comment
+			for current.ToID != 0 && depth <= len(legacy) {
+				var parent model.Comment
+				if err := tx.Unscoped().First(&parent, current.ToID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+					break
+				} else if err != nil {
+					return err
+				}
+				current, depth = parent, depth+1
+			}
+			if current.ToID == 0 {
+				rootID = current.ID
+			}
+			updates := map[string]interface{}{"root_id": rootID, "depth": depth}
+			if rootID == 0 {
+				updates = map[string]interface{}{"to_id": 0, "root_id": 0, "depth": 0}
+			}
+			if err := tx.Unscoped().Model(&comment).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE post SET comment_count = " +
+			"(SELECT COUNT(*) FROM comment WHERE comment.post_id = post.id AND comment.deleted_at IS NULL)").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE comment SET reply_count = 0").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE comment AS c JOIN (SELECT to_id, COUNT(*) AS n FROM comment " +
+			"WHERE to_id <> 0 AND deleted_at IS NULL GROUP BY to_id) AS t ON t.to_id = c.id SET c.reply_count = t.n").Error
+	})
+	if err != nil {
+		global.LOG.Warn("RepairCommentTree: repair comment tree error: ", err)
+	}
+}
+
+// RestoreComment 恢复被删除的评论及同一次删除的所有回复，被回复的评论必须未被删除
+func RestoreComment(comment model.Comment) error {
+	rootID := comment.RootID
+	if rootID == 0 {
+		rootID = comment.ID
+	}
+	tree := make([]model.Comment, 0)
+	global.DB.Unscoped().Where("root_id = ? AND deleted_at = ?", rootID, comment.DeletedAt.Time).Find(&tree)
+	children := make(map[uint64][]uint64)
+	for _, item := range tree {
+		children[item.ToID] = append(children[item.ToID], item.ID)
+	}
+	ids := []uint64{comment.ID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.Comment{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"deleted_by": 0, "deleted_at": nil}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Post{}).Where("id = ?", comment.PostID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + ?", len(ids))).Error; err != nil {
+			return err
+		}
+		if comment.ToID == 0 {
+			return nil
+		}
+		return tx.Model(&model.Comment{}).Where("id = ?", comment.ToID).
+			UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+}
+
+// DeletePost 软删除帖子
+func DeletePost(post model.Post, uid uint64) error {
+	return global.DB.Model(&post).UpdateColumns(map[string]interface{}{"deleted_by": uid, "deleted_at": time.Now()}).Error
+}
+
+// RestorePost 恢复被删除的帖子
+func RestorePost(post model.Post) error {
+	return global.DB.Unscoped().Model(&post).UpdateColumns(map[string]interface{}{"deleted_by": 0, "deleted_at": nil}).Error
+}
+
+// GetDeletedPostByID 通过ID获取被删除的帖子
+func GetDeletedPostByID(id uint64) (post model.Post, notFound bool) {
+	if err := global.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&post, id).Error; err != nil {
+		return post, true
+	}
+	return post, false
+}
+
+// GetDeletedCommentByID 通过ID获取被删除的评论
+func GetDeletedCommentByID(id uint64) (comment model.Comment, notFound bool) {
+	if err := global.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&comment, id).Error; err != nil {
+		return comment, true
+	}
+	return comment, false
+}
+
+// GetDeletedPosts 获取组织中仍在保留期内的被删除帖子，按删除时间降序排列
+func GetDeletedPosts(oid uint64) (posts []model.Post) {
+	global.DB.Unscoped().Where("org_id = ? AND deleted_at IS NOT NULL", oid).Order("deleted_at desc").Find(&posts)
 	return
 }
+
+// GetDeletedComments 获取组织中仍在保留期内的被删除评论，同一次删除的回复只返回最上层的评论
+func GetDeletedComments(oid uint64) (comments []model.Comment) {
+	global.DB.Unscoped().Where("org_id = ? AND deleted_at IS NOT NULL", oid).
+		Where("NOT EXISTS (SELECT 1 FROM comment AS p WHERE p.id = comment.to_id AND p.deleted_at = comment.deleted_at)").
+		Order("deleted_at desc").Find(&comments)
+	return
+}
+
+// PurgeDeletedForum 永久删除保留期已过的帖子与评论，以及相关的表情回应、举报、修订版本与订阅，由定时任务调用
+func PurgeDeletedForum() {
+	deadline := time.Now().Add(-ForumAuditPeriod)
+	postIDs, commentIDs := make([]uint64, 0), make([]uint64, 0)
+	global.DB.Unscoped().Model(&model.Post{}).Where("deleted_at < ?", deadline).Pluck("id", &postIDs)
+	global.DB.Unscoped().Model(&model.Comment{}).Where("deleted_at < ? OR post_id IN ?", deadline, append(postIDs, 0)).Pluck("id", &commentIDs)
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		for targetType, ids := range map[int][]uint64{ForumTargetPost: postIDs, ForumTargetComment: commentIDs} {
+			if len(ids) == 0 {
+				continue
+			}
+			if err := tx.Where("target_type = ? AND target_id IN ?", targetType, ids).Delete(&model.Reaction{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("target_type = ? AND target_id IN ?", targetType, ids).Delete(&model.Report{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("target_type = ? AND target_id IN ?", targetType, ids).Delete(&model.ForumRevision{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(postIDs) > 0 {
+			if err := tx.Where("target_type = ? AND target_id IN ?", SubscriptionTypePost, postIDs).Delete(&model.Subscription{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(commentIDs) > 0 {
+			if err := tx.Unscoped().Where("id IN ?", commentIDs).Delete(&model.Comment{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(postIDs) > 0 {
+			return tx.Unscoped().Where("id IN ?", postIDs).Delete(&model.Post{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		global.LOG.Warn("PurgeDeletedForum: purge deleted posts and comments error: ", err)
+	}
+}
+
+// UpdatePost 更新帖子，为空的字段不修改；标题或内容变化时记录修订版本，并更新传入的帖子
+func UpdatePost(post *model.Post, title string, content string, postType int, editorID uint64) error {
+	old := *post
+	if title != "" {
+		post.Title = title
+	}
+	if content != "" {
+		post.Content = content
+	}
+	if postType != 0 {
+		post.Type = postType
+	}
+	if post.Title == old.Title && post.Content == old.Content {
+		return global.DB.Model(post).Update("type", post.Type).Error
+	}
+	now := time.Now()
+	post.EditCount, post.EditedAt = old.EditCount+1, &now
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		post.ContentHTML = utils.RenderMarkdown(post.Content)
+		if err := tx.Model(post).Updates(map[string]interface{}{
+			"title": post.Title, "content": post.Content, "content_html": post.ContentHTML, "type": post.Type,
+			"edit_count": gorm.Expr("edit_count + 1"), "edited_at": now}).Error; err != nil {
+			return err
+		}
+		original := model.ForumRevision{EditorID: old.CreatorID, Title: old.Title, Content: old.Content, CreatedAt: originalTime(old.CreatedAt, old.UpdatedAt)}
+		return saveRevision(tx, ForumTargetPost, post.ID, original, model.ForumRevision{EditorID: editorID, Title: post.Title, Content: post.Content})
+	})
+	// 与发帖时一致，题解帖子中的提及不发送通知
+	if err == nil && !post.IsSolution {
+		NotifyNewMentions(editorID, post.OrgID, post.ID, 0, old.Content, post.Content)
+	}
+	return err
+}
+
+// UpdateComment 更新评论，内容变化时记录修订版本
+func UpdateComment(comment model.Comment, content string, editorID uint64) error {
+	if content == "" || content == comment.Content {
+		return nil
+	}
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Updates(map[string]interface{}{
+			"content": content, "content_html": utils.RenderMarkdown(content), "edit_count": gorm.Expr("edit_count + 1"), "edited_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		original := model.ForumRevision{EditorID: comment.CreatorID, Content: comment.Content, CreatedAt: originalTime(comment.CreatedAt, comment.UpdatedAt)}
+		return saveRevision(tx, ForumTargetComment, comment.ID, original, model.ForumRevision{EditorID: editorID, Content: content})
+	})
+	if err == nil {
+		if post, notFound := GetPostByID(comment.PostID); !notFound && !post.IsSolution {
+			NotifyNewMentions(editorID, comment.OrgID, comment.PostID, comment.ID, comment.Content, content)
+		}
+	}
+	return err
+}
+
+// originalTime 原始版本的创建时间，旧数据没有创建时间时以最近一次更新时间代替
+func originalTime(createdAt *time.Time, updatedAt time.Time) time.Time {
+	if createdAt != nil {
+		return *createdAt
+	}
+	return updatedAt
+}
+
+// saveRevision 记录一个修订版本，对象还没有修订记录时，先补记编辑前的原始版本
+func saveRevision(tx *gorm.DB, targetType int, targetID uint64, original model.ForumRevision, revision model.ForumRevision) error {
+	var version int
+	if err := tx.Model(&model.ForumRevision{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return err
+	}
+	if version == 0 {
+		original.TargetType, original.TargetID, original.Version = targetType, targetID, 1
+		if err := tx.Create(&original).Error; err != nil {
+			return err
+		}
+		version = 1
+	}
+	revision.TargetType, revision.TargetID, revision.Version = targetType, targetID, version+1
+	return tx.Create(&revision).Error
+}
+
+// GetRevisions 获取帖子或评论的所有修订版本，按版本号升序排列
+func GetRevisions(targetType int, targetID uint64) (revisions []model.ForumRevision) {
+	global.DB.Where("target_type = ? AND target_id = ?", targetType, targetID).Order("version").Find(&revisions)
+	return
+}
+
+// ModeratePost 管理员置顶、锁定帖子或将帖子移动到其他板块
+func ModeratePost(post model.Post, updates map[string]interface{}) error {
+	return global.DB.Model(&post).UpdateColumns(updates).Error
+}
+
+// GetReportByID 通过ID获取举报
+func GetReportByID(id uint64) (report model.Report, notFound bool) {
+	if err := global.DB.First(&report, id).Error; err != nil {
+		return report, true
+	}
+	return report, false
+}
+
+// HasPendingReport 判断用户是否已举报过该对象且尚未处理
+func HasPendingReport(uid uint64, targetType int, targetID uint64) bool {
+	var count int64
+	global.DB.Model(&model.Report{}).Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+		uid, targetType, targetID, ReportStatusPending).Count(&count)
+	return count > 0
+}
+
+// GetReports 获取组织审核队列中的举报，status为负数表示不限状态，按时间降序排列
+func GetReports(oid uint64, status int) (reports []model.Report) {
+	db := global.DB.Where("org_id = ?", oid)
+	if status >= 0 {
+		db = db.Where("status = ?", status)
+	}
+	db.Order("id desc").Find(&reports)
+	return
+}
+
+// HandleReport 处理举报，标记为已处理或已驳回
+func HandleReport(report model.Report, status int, uid uint64) error {
+	return global.DB.Model(&report).Updates(map[string]interface{}{"status": status, "handler_id": uid, "handled_at": time.Now()}).Error
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// 私信相关的参数
+const (
+	MessagePageSize        = 20   // 每页的消息数
+	MessageMaxLength       = 2048 // 单条消息的最大字符数
+	ConversationMaxMembers = 10   // 群聊的最大人数，包括创建者
+	ConversationTitleMax   = 32   // 群聊名称的最大字符数
+)
+
+// Helper
+
+// GetPairKey 获取单聊双方对应的会话标识，与双方的顺序无关
+func GetPairKey(uid1 uint64, uid2 uint64) string {
+	if uid1 > uid2 {
+		uid1, uid2 = uid2, uid1
+	}
+	return strconv.FormatUint(uid1, 10) + "-" + strconv.FormatUint(uid2, 10)
+}
+
+// GetConversationFromParam 从路径参数中获取会话，并判断当前用户是否为会话成员
+func GetConversationFromParam(c *gin.Context, uid uint64) (conversation model.Conversation, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		global.LOG.Warn("GetConversationFromParam: id invalid")
+		return conversation, false
+	}
+	if err := global.DB.First(&conversation, id).Error; err != nil {
+		return conversation, false
+	}
+	return conversation, IsConversationMember(conversation.ID, uid)
+}
+
+// BuildMessages 批量获取发送者信息，生成消息列表
+func BuildMessages(messages []model.Message) []model.MessageT {
+	senderIDs := make([]uint64, 0)
+	for _, message := range messages {
+		senderIDs = append(senderIDs, message.SenderID)
+	}
+	senders := GetUsersByIDs(senderIDs)
+	res := make([]model.MessageT, 0)
+	for _, message := range messages {
+		res = append(res, model.MessageT{
+			ID:             message.ID,
+			ConversationID: message.ConversationID,
+			SenderID:       message.SenderID,
+			SenderName:     senders[message.SenderID].Name,
+			SenderAvatar:   senders[message.SenderID].Avatar,
+			Content:        message.Content,
+			CreatedAt:      message.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// BuildConversations 批量获取会话成员、最近一条消息与未读数，生成会话列表
+func BuildConversations(uid uint64, conversations []model.Conversation) []model.ConversationT {
+	conversationIDs, messageIDs := make([]uint64, 0), make([]uint64, 0)
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+		messageIDs = append(messageIDs, conversation.LastMessageID)
+	}
+	// 会话成员
+	members := make([]model.ConversationMember, 0)
+	memberIDs := make([]uint64, 0)
+	if len(conversationIDs) > 0 {
+		global.DB.Where("conversation_id IN ?", conversationIDs).Order("id").Find(&members)
+	}
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.UserID)
+	}
+	users := GetUsersByIDs(memberIDs)
+	memberMap := make(map[uint64][]model.ConversationMemberT)
+	for _, member := range members {
+		user := users[member.UserID]
+		memberMap[member.ConversationID] = append(memberMap[member.ConversationID],
+			model.ConversationMemberT{UserID: user.ID, Name: user.Name, Avatar: user.Avatar})
+	}
+	// 最近一条消息
+	messages := make([]model.Message, 0)
+	if len(messageIDs) > 0 {
+		global.DB.Where("id IN ?", messageIDs).Find(&messages)
+	}
+	lastMessages := make(map[uint64]model.MessageT)
+	for _, message := range BuildMessages(messages) {
+		lastMessages[message.ConversationID] = message
+	}
+	unread := CountUnreadMessagesByConversation(uid)
+	res := make([]model.ConversationT, 0)
+	for _, conversation := range conversations {
+		item := model.ConversationT{
+			ID:          conversation.ID,
+			IsGroup:     conversation.IsGroup,
+			Title:       conversation.Title,
+			Members:     memberMap[conversation.ID],
+			UnreadCount: unread[conversation.ID]}
+		if message, ok := lastMessages[conversation.ID]; ok {
+			item.LastMessage = &message
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// BuildBlocks 批量获取被屏蔽用户的信息，生成屏蔽列表
+func BuildBlocks(blocks []model.UserBlock) []model.BlockT {
+	blockedIDs := make([]uint64, 0)
+	for _, block := range blocks {
+		blockedIDs = append(blockedIDs, block.BlockedID)
+	}
+	users := GetUsersByIDs(blockedIDs)
+	res := make([]model.BlockT, 0)
+	for _, block := range blocks {
+		res = append(res, model.BlockT{
+			UserID:    block.BlockedID,
+			Name:      users[block.BlockedID].Name,
+			Avatar:    users[block.BlockedID].Avatar,
+			CreatedAt: block.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// 数据库操作
+
+// ShareOrganization 判断两个用户是否至少同属一个组织
+func ShareOrganization(uid1 uint64, uid2 uint64) bool {
+	var count int64
+	global.DB.Model(&model.Invitation{}).Where("user_id = ? AND is_valid = ?", uid2, true).
+		Where("org_id IN (?)", global.DB.Model(&model.Invitation{}).Select("org_id").Where("user_id = ? AND is_valid = ?", uid1, true)).
+		Count(&count)
+	return count > 0
+}
+
+// IsBlocked 判断两个用户之间是否有一方屏蔽了另一方
+func IsBlocked(uid1 uint64, uid2 uint64) bool {
+	var count int64
+	global.DB.Model(&model.UserBlock{}).
+		Where("(user_id = ? AND blocked_id = ?) OR (user_id = ? AND blocked_id = ?)", uid1, uid2, uid2, uid1).Count(&count)
+	return count > 0
+}
+
+// GetBlocks 获取用户屏蔽的所有用户，按时间降序排列
+func GetBlocks(uid uint64) (blocks []model.UserBlock) {
+	global.DB.Where("user_id = ?", uid).Order("id desc").Find(&blocks)
+	return
+}
+
+// BlockUser 屏蔽用户，已屏蔽时不做修改
+func BlockUser(uid uint64, blockedID uint64) error {
+	return global.DB.Where(model.UserBlock{UserID: uid, BlockedID: blockedID}).FirstOrCreate(&model.UserBlock{}).Error
+}
+
+// UnblockUser 取消屏蔽用户
+func UnblockUser(uid uint64, blockedID uint64) error {
+	return global.DB.Where("user_id = ? AND blocked_id = ?", uid, blockedID).Delete(&model.UserBlock{}).Error
+}
+
+// IsConversationMember 判断用户是否为会话成员
+func IsConversationMember(conversationID uint64, uid uint64) bool {
+	var count int64
+	global.DB.Model(&model.ConversationMember{}).Where("conversation_id = ? AND user_id = ?", conversationID, uid).Count(&count)
+	return count > 0
+}
+
+// GetConversationMemberIDs 获取会话的所有成员ID
+func GetConversationMemberIDs(conversationID uint64) (ids []uint64) {
+	global.DB.Model(&model.ConversationMember{}).Where("conversation_id = ?", conversationID).Pluck("user_id", &ids)
+	return
+}
+
+// GetConversations 获取用户参与的所有会话，按最近一条消息的时间降序排列
+func GetConversations(uid uint64) (conversations []model.Conversation) {
+	global.DB.Where("id IN (?)", global.DB.Model(&model.ConversationMember{}).Select("conversation_id").Where("user_id = ?", uid)).
+		Order("COALESCE(last_message_time, created_at) desc").Find(&conversations)
+	return
+}
+
+// GetPrivateConversation 获取两个用户之间的单聊会话
+func GetPrivateConversation(uid1 uint64, uid2 uint64) (conversation model.Conversation, notFound bool) {
+	if err := global.DB.Where("pair_key = ? AND is_group = ?", GetPairKey(uid1, uid2), false).First(&conversation).Error; err != nil {
+		return conversation, true
+	}
+	return conversation, false
+}
+
+// CreateConversation 创建会话，并将创建者与其他成员加入会话；
+// 并发创建同一单聊时，唯一索引使后创建者失败，此时conversation被替换为已存在的会话
+func CreateConversation(conversation *model.Conversation, memberIDs []uint64) error {
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		members := []model.ConversationMember{{ConversationID: conversation.ID, UserID: conversation.CreatorID}}
+		for _, id := range memberIDs {
+			members = append(members, model.ConversationMember{ConversationID: conversation.ID, UserID: id})
+		}
+		return tx.Create(&members).Error
+	})
+	if err != nil && !conversation.IsGroup && utils.IsDuplicateKeyError(err) {
+		if existing, notFound := GetPrivateConversation(conversation.CreatorID, memberIDs[0]); !notFound {
+			*conversation = existing
+			return nil
+		}
+	}
+	return err
+}
+
+// LeaveConversation 用户退出群聊
+func LeaveConversation(conversation model.Conversation, uid uint64) error {
+	return global.DB.Where("conversation_id = ? AND user_id = ?", conversation.ID, uid).Delete(&model.ConversationMember{}).Error
+}
+
+// SendMessage 在会话中发送消息，发送者的消息视为已读，并向未屏蔽发送者的成员推送新消息
+func SendMessage(conversation model.Conversation, message *model.Message) error {
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&conversation).UpdateColumns(map[string]interface{}{
+			"last_message_id": message.ID, "last_message_time": message.CreatedAt}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.ConversationMember{}).Where("conversation_id = ? AND user_id = ?", conversation.ID, message.SenderID).
+			Update("last_read_message_id", message.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	blockers := make([]uint64, 0)
+	global.DB.Model(&model.UserBlock{}).Where("blocked_id = ?", message.SenderID).Pluck("user_id", &blockers)
+	blocked := make(map[uint64]bool)
+	for _, id := range blockers {
+		blocked[id] = true
+	}
+	data := BuildMessages([]model.Message{*message})[0]
+	for _, uid := range GetConversationMemberIDs(conversation.ID) {
+		if !blocked[uid] {
+			PublishEvent(Event{Type: EventTypeMessage, UserID: uid, Data: data})
+		}
+	}
+	return nil
+}
+
+// messagesVisibleTo 过滤掉用户屏蔽的发送者的消息
+func messagesVisibleTo(db *gorm.DB, uid uint64) *gorm.DB {
+	return db.Where("sender_id NOT IN (?)", global.DB.Model(&model.UserBlock{}).Select("blocked_id").Where("user_id = ?", uid))
+}
+
+// GetMessagesByPage 分页获取会话中的消息，按时间降序排列，不包括用户屏蔽的发送者的消息
+func GetMessagesByPage(conversationID uint64, uid uint64, page int) (messages []model.Message, total int64) {
+	db := messagesVisibleTo(global.DB.Model(&model.Message{}).Where("conversation_id = ?", conversationID), uid)
+	db.Count(&total)
+	db.Order("id desc").Offset((page - 1) * MessagePageSize).Limit(MessagePageSize).Find(&messages)
+	return
+}
+
+// ReadConversation 将会话中的消息全部标记为已读
+func ReadConversation(conversation model.Conversation, uid uint64) error {
+	return global.DB.Model(&model.ConversationMember{}).Where("conversation_id = ? AND user_id = ?", conversation.ID, uid).
+		Update("last_read_message_id", conversation.LastMessageID).Error
+}
+
+// CountUnreadMessagesByConversation 统计用户在每个会话中的未读消息数，不包括用户屏蔽的发送者的消息
+func CountUnreadMessagesByConversation(uid uint64) map[uint64]int64 {
+	rows := make([]struct {
+		ConversationID uint64
+		Count          int64
+	}, 0)
+	messagesVisibleTo(global.DB.Model(&model.Message{}), uid).
+		Select("message.conversation_id, COUNT(*) AS count").
+		Joins("JOIN conversation_member ON conversation_member.conversation_id = message.conversation_id").
+		Where("conversation_member.user_id = ? AND message.id > conversation_member.last_read_message_id AND message.sender_id <> ?", uid, uid).
+		Group("message.conversation_id").Scan(&rows)
+	res := make(map[uint64]int64)
+	for _, row := range rows {
+		res[row.ConversationID] = row.Count
+	}
+	return res
+}
+
+// CountUnreadMessages 统计用户在所有会话中的未读消息总数
+func CountUnreadMessages(uid uint64) (count int64) {
+	for _, n := range CountUnreadMessagesByConversation(uid) {
+		count += n
+	}
+	return
+}
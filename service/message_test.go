@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestGetPairKey(t *testing.T) {
+	tests := []struct {
+		uid1, uid2 uint64
+		want       string
+	}{
+		{1, 2, "1-2"},
+		{2, 1, "1-2"},
+		{9, 10, "9-10"}, // 按数值而非字符串比较大小
+		{10, 9, "9-10"},
+		{5, 5, "5-5"},
+	}
+	for _, tt := range tests {
+		if got := GetPairKey(tt.uid1, tt.uid2); got != tt.want {
+			t.Errorf("GetPairKey(%d, %d) = %q, want %q", tt.uid1, tt.uid2, got, tt.want)
+		}
+	}
+}
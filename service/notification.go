@@ -0,0 +1,216 @@
+package service
+
+import (
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"gorm.io/gorm/clause"
+	"regexp"
+)
+
+// 通知的类型
+const (
+	NotificationTypeReply      = 1
+	NotificationTypeMention    = 2
+	NotificationTypeInvitation = 3
+	NotificationTypeJudge      = 4
+)
+
+// NotificationTypes 所有通知类型，用于列出用户的通知偏好
+var NotificationTypes = []int{NotificationTypeReply, NotificationTypeMention, NotificationTypeInvitation, NotificationTypeJudge}
+
+// NotificationPageSize 每页的通知数
+const NotificationPageSize = 20
+
+// mentionPattern 内容中的@提及，用户名到空白字符、下一个@或中英文标点为止，下划线与连字符视为用户名的一部分
+var mentionPattern = regexp.MustCompile(`@((?:[_\-]|[^\s@\p{P}\p{S}])+)`)
+
+// Helper
+
+// ParseMentions 解析内容中被@提及的用户名，结果去重
+func ParseMentions(content string) (names []string) {
+	names = make([]string, 0)
+	visited := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if !visited[match[1]] {
+			visited[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return
+}
+
+// TruncateContent 截取内容的前80个字符作为摘要
+func TruncateContent(content string) string {
+	if runes := []rune(content); len(runes) > 80 {
+		return string(runes[:80]) + "…"
+	}
+	return content
+}
+
+// Notify 发送一条通知，不通知用户自己触发的回复与提及，也不发送用户关闭的类型，发送失败仅记录日志
+func Notify(notification model.Notification) {
+	if notification.UserID == 0 || (notification.UserID == notification.ActorID && notification.Type != NotificationTypeJudge) {
+		return
+	}
+	if !IsNotificationEnabled(notification.UserID, notification.Type) {
+		return
+	}
+	notification.Content = TruncateContent(notification.Content)
+	if err := global.DB.Create(&notification).Error; err != nil {
+		global.LOG.Warn("Notify: create notification error: ", err)
+		return
+	}
+	PublishEvent(Event{
+		Type:   EventTypeNotification,
+		UserID: notification.UserID,
+		Data:   BuildNotifications([]model.Notification{notification})[0]})
+}
+
+// NotifyMentions 通知内容中被@提及的组织成员，excluded中的用户已收到其他通知，不再重复通知
+func NotifyMentions(actorID uint64, orgID uint64, postID uint64, commentID uint64, content string, excluded map[uint64]bool) {
+	names := make(map[string]bool)
+	for _, name := range ParseMentions(content) {
+		names[name] = true
+	}
+	notifyMentionedMembers(actorID, orgID, postID, commentID, content, names, excluded)
+}
+
+// NotifyNewMentions 内容被编辑后，只通知新增的@提及，编辑前已提及的用户不再重复通知
+func NotifyNewMentions(actorID uint64, orgID uint64, postID uint64, commentID uint64, oldContent string, content string) {
+	oldNames := make(map[string]bool)
+	for _, name := range ParseMentions(oldContent) {
+		oldNames[name] = true
+	}
+	names := make(map[string]bool)
+	for _, name := range ParseMentions(content) {
+		if !oldNames[name] {
+			names[name] = true
+		}
+	}
+	notifyMentionedMembers(actorID, orgID, postID, commentID, content, names, nil)
+}
+
+// notifyMentionedMembers 通知用户名在names中的组织成员
+func notifyMentionedMembers(actorID uint64, orgID uint64, postID uint64, commentID uint64, content string, names map[string]bool, excluded map[uint64]bool) {
+	if len(names) == 0 {
+		return
+	}
+	memberIDs := make([]uint64, 0)
+	for _, member := range GetOrganizationMember(orgID) {
+		memberIDs = append(memberIDs, member.ID)
+	}
+	for _, user := range GetUsersByIDs(memberIDs) {
+		if names[user.Name] && !excluded[user.ID] {
+			Notify(model.Notification{
+				UserID:   user.ID,
+				Type:     NotificationTypeMention,
+				ActorID:  actorID,
+				TargetID: postID,
+				SourceID: commentID,
+				Content:  content})
+		}
+	}
+}
+
+// BuildNotifications 批量获取触发者信息，生成通知列表
+func BuildNotifications(notifications []model.Notification) []model.NotificationT {
+	actorIDs := make([]uint64, 0)
+	for _, notification := range notifications {
+		actorIDs = append(actorIDs, notification.ActorID)
+	}
+	actors := GetUsersByIDs(actorIDs)
+	res := make([]model.NotificationT, 0)
+	for _, notification := range notifications {
+		res = append(res, model.NotificationT{
+			ID:          notification.ID,
+			Type:        notification.Type,
+			ActorID:     notification.ActorID,
+			ActorName:   actors[notification.ActorID].Name,
+			ActorAvatar: actors[notification.ActorID].Avatar,
+			TargetID:    notification.TargetID,
+			SourceID:    notification.SourceID,
+			Content:     notification.Content,
+			IsRead:      notification.IsRead,
+			CreatedAt:   notification.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// GetResultName 获取评测结果的名称
+func GetResultName(result int) string {
+	names := []string{"AC", "WA", "TLE", "RE"}
+	if result < 0 || result >= len(names) {
+		return "UNKNOWN"
+	}
+	return names[result]
+}
+
+// 数据库操作
+
+// GetNotificationsByPage 分页获取用户的通知，按时间降序排列
+func GetNotificationsByPage(uid uint64, page int, unreadOnly bool) (notifications []model.Notification, total int64) {
+	db := global.DB.Model(&model.Notification{}).Where("user_id = ?", uid)
+	if unreadOnly {
+		db = db.Where("is_read = ?", false)
+	}
+	db.Count(&total)
+	db.Order("id desc").Offset((page - 1) * NotificationPageSize).Limit(NotificationPageSize).Find(&notifications)
+	return
+}
+
+// CountUnreadNotifications 统计用户的未读通知数
+func CountUnreadNotifications(uid uint64) (count int64) {
+	global.DB.Model(&model.Notification{}).Where("user_id = ? AND is_read = ?", uid, false).Count(&count)
+	return
+}
+
+// ReadNotifications 将用户的若干通知标记为已读，ids为空表示全部标记为已读
+func ReadNotifications(uid uint64, ids []uint64) error {
+	db := global.DB.Model(&model.Notification{}).Where("user_id = ? AND is_read = ?", uid, false)
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	return db.Update("is_read", true).Error
+}
+
+// IsNotificationEnabled 判断用户是否接收某类通知
+func IsNotificationEnabled(uid uint64, notificationType int) bool {
+	var preference model.NotificationPreference
+	if err := global.DB.Where("user_id = ? AND type = ?", uid, notificationType).First(&preference).Error; err != nil {
+		return true
+	}
+	return preference.Enabled
+}
+
+// GetNotificationPreferences 获取用户对所有通知类型的偏好
+func GetNotificationPreferences(uid uint64) []model.NotificationPreferenceT {
+	rows := make([]model.NotificationPreference, 0)
+	global.DB.Where("user_id = ?", uid).Find(&rows)
+	enabled := make(map[int]bool)
+	for _, notificationType := range NotificationTypes {
+		enabled[notificationType] = true
+	}
+	for _, row := range rows {
+		enabled[row.Type] = row.Enabled
+	}
+	preferences := make([]model.NotificationPreferenceT, 0)
+	for _, notificationType := range NotificationTypes {
+		preferences = append(preferences, model.NotificationPreferenceT{Type: notificationType, Enabled: enabled[notificationType]})
+	}
+	return preferences
+}
+
+// UpdateNotificationPreferences 更新用户的通知偏好
+func UpdateNotificationPreferences(uid uint64, preferences []model.NotificationPreferenceT) error {
+	rows := make([]model.NotificationPreference, 0)
+	for _, preference := range preferences {
+		rows = append(rows, model.NotificationPreference{UserID: uid, Type: preference.Type, Enabled: preference.Enabled})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&rows).Error
+}
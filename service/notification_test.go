@@ -0,0 +1,31 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"没有提及", "hello world", []string{}},
+		{"空白字符结束", "@alice hello", []string{"alice"}},
+		{"英文标点结束", "thanks @alice, @bob. @carol!", []string{"alice", "bob", "carol"}},
+		{"中文标点结束", "@张三，你好。@李四：请看", []string{"张三", "李四"}},
+		{"括号与引号结束", "（@alice）“@bob”", []string{"alice", "bob"}},
+		{"连续提及", "@alice@bob", []string{"alice", "bob"}},
+		{"下划线与连字符", "@a_b-c?", []string{"a_b-c"}},
+		{"结果去重", "@alice @alice，@alice", []string{"alice"}},
+		{"单独的@", "@ @，", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Helper
@@ -25,11 +26,12 @@ func GetProblemFileUrl(problem *model.Problem, kind string) string {
 	return "resource/problem/" + GetProblemFileFolder(problem.ID, problem.Version) + "/" + kind
 }
 
-// GetReadableProblems 获取所有可访问问题
+// GetReadableProblems 获取所有可访问问题，位于未结束比赛中的题目仅对创建者与组织管理员可见
 func GetReadableProblems(c *gin.Context) (problems []model.Problem) {
 	user := utils.SolveUser(c)
 	allProblems := QueryAllProblems()
 	problems = make([]model.Problem, 0)
+	locked, published := GetContestProblemVisibility()
 
 	// 创建组织到是否是组织管理员的映射
 	orgAdminMap := make(map[uint64]bool)
@@ -37,10 +39,14 @@ func GetReadableProblems(c *gin.Context) (problems []model.Problem) {
 		orgAdminMap[invitation.OrgID] = invitation.IsAdmin
 	}
 	for _, problem := range allProblems {
-		if problem.Readable == 3 || problem.Creator == user.ID {
+		isAdmin, ok := orgAdminMap[problem.OrgID]
+		if problem.Creator == user.ID || (ok && isAdmin) {
+			// 题目创建者，或在题目的组织中且是管理员，直接加入
 			problems = append(problems, problem)
-		} else if isAdmin, ok := orgAdminMap[problem.OrgID]; ok && isAdmin {
-			// 在题目的组织中且是管理员，直接加入
+		} else if locked[problem.ID] {
+			// 题目位于未结束的比赛中，对其他用户隐藏
+			continue
+		} else if problem.Readable == 3 || published[problem.ID] {
 			problems = append(problems, problem)
 		} else if ok && !isAdmin && problem.Readable == 2 {
 			// 在题目所属组织中，但不是管理员，仅当可读为2
@@ -51,6 +57,43 @@ func GetReadableProblems(c *gin.Context) (problems []model.Problem) {
 	return problems
 }
 
+// JudgeProblemReadPermission 判别用户对题目的可读权限
+// 位于未结束比赛中的题目，仅对创建者、组织管理员以及正在进行的比赛的参赛者可读；
+// 比赛结束后恢复原有的可读权限，若比赛设置了公开题目，则对所有人可读；虚拟参赛期间对虚拟参赛者可读
+func JudgeProblemReadPermission(problem model.Problem, c *gin.Context) bool {
+	return judgeProblemReadable(problem, utils.SolveUser(c).ID)
+}
+
+// judgeProblemReadable 根据用户ID判别对题目的可读权限，用于没有请求上下文的场景，如订阅摘要
+func judgeProblemReadable(problem model.Problem, uid uint64) bool {
+	if problem.Creator == uid || IsOrganizationAdmin(uid, problem.OrgID) {
+		return true
+	}
+	locked, published := false, false
+	for _, contest := range GetProblemContests(problem.ID) {
+		if time.Now().Before(contest.EndTime) {
+			locked = true
+			if IsOrganizationAdmin(uid, contest.OrgID) ||
+				(IsContestRunning(contest) && JudgeContestReadPermission(uid, contest)) {
+				return true
+			}
+		} else if contest.PublishProblems {
+			published = true
+		}
+	}
+	if locked {
+		return false
+	} else if published {
+		return true
+	}
+	// 正在虚拟参加包含该题目的比赛时，与正式参赛者一样可读
+	if _, notFound := GetActiveVirtualContest(uid, problem.ID); !notFound {
+		return true
+	}
+	invitation, notFound := GetInvitationByUserOrg(uid, problem.OrgID)
+	return judgeReadable(problem.Readable, problem.Creator, uid, !notFound, invitation.IsAdmin)
+}
+
 // GetProblemsByPage 对给定问题做出排序与页选择
 func GetProblemsByPage(problems []model.Problem, page int, sorter int) (problemList []model.Problem) {
 	size := 10
@@ -123,6 +166,23 @@ func UpdateProblem(problem *model.Problem, q *model.UpdateProblemQ) (err error)
 	return err
 }
 
+// RecordProblemVersion 记录题目当前的版本
+func RecordProblemVersion(problem model.Problem) error {
+	return global.DB.Create(&model.ProblemVersion{ProblemID: problem.ID, Version: problem.Version}).Error
+}
+
+// GetProblemVersionAt 获取题目在某一时刻使用的版本，没有版本记录的旧题目使用当前版本
+func GetProblemVersionAt(problem model.Problem, t time.Time) int {
+	var version model.ProblemVersion
+	err := global.DB.Where("problem_id = ? AND created_time <= ?", problem.ID, t).Order("version desc").First(&version).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return problem.Version
+	} else if err != nil {
+		global.LOG.Panic("GetProblemVersionAt: search error")
+	}
+	return version.Version
+}
+
 // SaveProblem 根据信息保存题目
 func SaveProblem(problem *model.Problem) (err error) {
 	err = global.DB.Save(problem).Error
@@ -136,6 +196,36 @@ func QueryAllProblems() (problems []model.Problem) {
 	return problems
 }
 
+// GetProblemContests 获取包含某题目的所有比赛
+func GetProblemContests(problemID uint64) (contests []model.Contest) {
+	contests = make([]model.Contest, 0)
+	global.DB.Where("id IN (?)", global.DB.Model(&model.ContestProblem{}).Select("contest_id").Where("problem_id = ?", problemID)).
+		Find(&contests)
+	return contests
+}
+
+// GetContestProblemVisibility 获取受比赛影响可见性的题目
+// locked为位于未结束比赛中的题目，published为位于已结束且设置了公开题目的比赛中的题目
+func GetContestProblemVisibility() (locked map[uint64]bool, published map[uint64]bool) {
+	locked, published = make(map[uint64]bool), make(map[uint64]bool)
+	now := time.Now()
+	rows := make([]struct {
+		ProblemID uint64
+		EndTime   time.Time
+	}, 0)
+	global.DB.Model(&model.ContestProblem{}).Select("contest_problem.problem_id, contest.end_time").
+		Joins("JOIN contest ON contest.id = contest_problem.contest_id").
+		Where("contest.end_time > ? OR contest.publish_problems = ?", now, true).Scan(&rows)
+	for _, row := range rows {
+		if now.Before(row.EndTime) {
+			locked[row.ProblemID] = true
+		} else {
+			published[row.ProblemID] = true
+		}
+	}
+	return locked, published
+}
+
 // QueryUserProblemResult 查询用户对某问题的所有评测结果
 func QueryUserProblemResult(uid uint64, pid uint64) (results []model.Result) {
 	global.DB.Where("user_id = ? AND problem_id = ?", uid, pid).Find(&results)
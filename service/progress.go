@@ -0,0 +1,107 @@
+package service
+
+import (
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+)
+
+// Helper
+
+// CalculateTutorialProgress 根据用户已学完的课时与已通过的题目，计算用户在教程中的学习进度
+// 同一题目嵌入多个课时时，在每个课时中分别计数，在教程的题目总数与通过数中只计一次
+func CalculateTutorialProgress(lessons []model.TutorialLesson, lessonProblems map[uint64][]uint64,
+	read map[uint64]bool, accepted map[uint64]bool) (progress model.TutorialProgressT, items []model.LessonProgressT) {
+	items = make([]model.LessonProgressT, 0)
+	counted := make(map[uint64]bool)
+	for _, lesson := range lessons {
+		item := model.LessonProgressT{
+			LessonID:  lesson.ID,
+			ChapterID: lesson.ChapterID,
+			Title:     lesson.Title,
+			IsRead:    read[lesson.ID]}
+		visited := make(map[uint64]bool)
+		for _, problemID := range lessonProblems[lesson.ID] {
+			if visited[problemID] {
+				continue
+			}
+			visited[problemID] = true
+			item.Total++
+			if accepted[problemID] {
+				item.Solved++
+			}
+			if !counted[problemID] {
+				counted[problemID] = true
+				progress.TotalProblems++
+				if accepted[problemID] {
+					progress.SolvedProblems++
+				}
+			}
+		}
+		item.Completed = item.IsRead && item.Solved == item.Total
+		if item.Completed {
+			progress.CompletedLessons++
+		}
+		items = append(items, item)
+	}
+	progress.TotalLessons = len(lessons)
+	return
+}
+
+// GetTutorialProblemIDs 获取教程所有课时中嵌入的练习题，返回课时ID到练习题ID列表的映射以及去重后的所有练习题ID
+func GetTutorialProblemIDs(tutorialID uint64) (lessonProblems map[uint64][]uint64, problemIDs []uint64) {
+	lessonProblems = make(map[uint64][]uint64)
+	problemIDs = make([]uint64, 0)
+	visited := make(map[uint64]bool)
+	for _, rel := range QueryTutorialLessonProblems(tutorialID) {
+		lessonProblems[rel.LessonID] = append(lessonProblems[rel.LessonID], rel.ProblemID)
+		if !visited[rel.ProblemID] {
+			visited[rel.ProblemID] = true
+			problemIDs = append(problemIDs, rel.ProblemID)
+		}
+	}
+	return
+}
+
+// 数据库操作
+
+// QueryTutorialLessonProblems 获取教程所有课时中嵌入的练习题关系
+func QueryTutorialLessonProblems(tutorialID uint64) (rels []model.TutorialLessonProblem) {
+	rels = make([]model.TutorialLessonProblem, 0)
+	lessonIDs := global.DB.Model(&model.TutorialLesson{}).Select("id").Where("tutorial_id = ?", tutorialID)
+	global.DB.Where("lesson_id IN (?)", lessonIDs).Order("position asc").Find(&rels)
+	return
+}
+
+// GetReadLessons 获取一组用户在教程中已学完的课时，返回用户ID到课时ID集合的映射
+func GetReadLessons(userIDs []uint64, tutorialID uint64) (read map[uint64]map[uint64]bool) {
+	read = make(map[uint64]map[uint64]bool)
+	progresses := make([]model.LessonProgress, 0)
+	global.DB.Where("user_id IN ? AND tutorial_id = ?", userIDs, tutorialID).Find(&progresses)
+	for _, progress := range progresses {
+		if read[progress.UserID] == nil {
+			read[progress.UserID] = make(map[uint64]bool)
+		}
+		read[progress.UserID][progress.LessonID] = true
+	}
+	return
+}
+
+// GetAcceptedProblems 获取一组用户在给定题目中已通过的题目，返回用户ID到题目ID集合的映射
+func GetAcceptedProblems(userIDs []uint64, problemIDs []uint64) (accepted map[uint64]map[uint64]bool) {
+	accepted = make(map[uint64]map[uint64]bool)
+	results := make([]model.Result, 0)
+	global.DB.Select("user_id", "problem_id").Where("user_id IN ? AND problem_id IN ? AND result = ?", userIDs, problemIDs, 0).Find(&results)
+	for _, result := range results {
+		if accepted[result.UserID] == nil {
+			accepted[result.UserID] = make(map[uint64]bool)
+		}
+		accepted[result.UserID][result.ProblemID] = true
+	}
+	return
+}
+
+// CompleteLesson 记录用户已学完课时内容，重复记录时忽略
+func CompleteLesson(uid uint64, lesson model.TutorialLesson) error {
+	progress := model.LessonProgress{UserID: uid, TutorialID: lesson.TutorialID, LessonID: lesson.ID}
+	return global.DB.Where("user_id = ? AND lesson_id = ?", uid, lesson.ID).FirstOrCreate(&progress).Error
+}
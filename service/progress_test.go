@@ -0,0 +1,41 @@
+package service
+
+import (
+	"github.com/phoenix-next/phoenix-server/model"
+	"testing"
+)
+
+func TestCalculateTutorialProgress(t *testing.T) {
+	lessons := []model.TutorialLesson{{ID: 1, ChapterID: 1}, {ID: 2, ChapterID: 1}, {ID: 3, ChapterID: 2}}
+	lessonProblems := map[uint64][]uint64{
+		1: {10, 11},
+		2: {11, 12, 12}, // 题目11同时嵌入课时1与课时2，题目12在课时2中重复嵌入
+	}
+	read := map[uint64]bool{1: true, 2: true}
+	accepted := map[uint64]bool{10: true, 11: true}
+
+	progress, items := CalculateTutorialProgress(lessons, lessonProblems, read, accepted)
+	want := model.TutorialProgressT{CompletedLessons: 1, TotalLessons: 3, SolvedProblems: 2, TotalProblems: 3}
+	if progress != want {
+		t.Errorf("progress = %+v, want %+v", progress, want)
+	}
+	wantItems := []struct {
+		solved, total int
+		completed     bool
+	}{{2, 2, true}, {1, 2, false}, {0, 0, false}}
+	if len(items) != len(wantItems) {
+		t.Fatalf("len(items) = %d, want %d", len(items), len(wantItems))
+	}
+	for i, w := range wantItems {
+		if items[i].Solved != w.solved || items[i].Total != w.total || items[i].Completed != w.completed {
+			t.Errorf("items[%d] = %+v, want solved %d total %d completed %v", i, items[i], w.solved, w.total, w.completed)
+		}
+	}
+}
+
+func TestCalculateTutorialProgressEmpty(t *testing.T) {
+	progress, items := CalculateTutorialProgress(nil, nil, nil, nil)
+	if progress != (model.TutorialProgressT{}) || len(items) != 0 {
+		t.Errorf("progress = %+v, items = %v, want empty", progress, items)
+	}
+}
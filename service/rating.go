@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"sort"
+)
+
+// ErrContestFinalized 结算积分时，比赛已被结算
+var ErrContestFinalized = errors.New("contest already finalized")
+
+// Helper
+
+// winProbability 积分为ra的用户在排名上胜过积分为rb的用户的概率，即Elo公式
+func winProbability(ra float64, rb float64) float64 {
+	return 1 / (1 + math.Pow(10, (rb-ra)/400))
+}
+
+// expectedRank 积分为rating的用户在给定对手中的期望名次，exclude为该用户自身的下标
+func expectedRank(rating float64, ratings []int, exclude int) float64 {
+	rank := 1.0
+	for i, r := range ratings {
+		if i != exclude {
+			rank += winProbability(float64(r), rating)
+		}
+	}
+	return rank
+}
+
+// CalculateRatingDeltas 根据参赛者赛前的积分与最终名次，计算每个参赛者的积分变化量
+// 计算方式参考Codeforces的积分算法：
+//  1. 由Elo公式计算每个参赛者的期望名次seed
+//  2. 取期望名次与实际名次的几何平均数m，二分查找使期望名次恰为m的积分R，积分变化量为(R - 赛前积分) / 2
+//  3. 整体修正变化量，使所有参赛者的积分变化量之和略小于0，防止积分膨胀
+//  4. 对赛前积分最高的4 * sqrt(n)名参赛者再做修正，使其积分变化量之和不大于0，修正幅度至多为10
+func CalculateRatingDeltas(ratings []int, ranks []int) (deltas []int) {
+	n := len(ratings)
+	deltas = make([]int, n)
+	if n == 0 {
+		return
+	}
+	// 计算期望名次与实际名次对应的积分
+	for i := range ratings {
+		seed := expectedRank(float64(ratings[i]), ratings, i)
+		mid := math.Sqrt(seed * float64(ranks[i]))
+		left, right := 1.0, 8000.0
+		for right-left > 1 {
+			r := (left + right) / 2
+			if expectedRank(r, ratings, i) < mid {
+				right = r
+			} else {
+				left = r
+			}
+		}
+		deltas[i] = int((left - float64(ratings[i])) / 2)
+	}
+	// 整体修正，使积分变化量之和略小于0
+	sum := 0
+	for _, delta := range deltas {
+		sum += delta
+	}
+	inc := -sum/n - 1
+	for i := range deltas {
+		deltas[i] += inc
+	}
+	// 对赛前积分最高的参赛者修正
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return ratings[order[i]] > ratings[order[j]] })
+	size := int(math.Min(float64(n), 4*math.Sqrt(float64(n))))
+	sumTop := 0
+	for _, i := range order[:size] {
+		sumTop += deltas[i]
+	}
+	inc = int(math.Min(math.Max(float64(-sumTop/size), -10), 0))
+	for i := range deltas {
+		deltas[i] += inc
+	}
+	return
+}
+
+// FinalizeContestRating 根据比赛最终榜单结算积分，更新参赛者积分并保存积分变化历史
+// 在事务中先以条件更新占用结算标记，防止并发结算重复计分，并在事务中重新读取参赛者的积分
+func FinalizeContestRating(contest model.Contest) error {
+	standings := GetContestStandings(contest, contest.EndTime.Sub(contest.StartTime))
+	userIDs := make([]uint64, 0)
+	for _, standing := range standings {
+		userIDs = append(userIDs, standing.UserID)
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Contest{}).Where("id = ? AND is_finalized = ?", contest.ID, false).Update("is_finalized", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrContestFinalized
+		}
+		// 锁定参赛者并读取最新积分
+		users := make([]model.User, 0)
+		if len(userIDs) > 0 {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+				return err
+			}
+		}
+		userRatings := make(map[uint64]int)
+		for _, user := range users {
+			userRatings[user.ID] = user.Rating
+		}
+		ratings, ranks := make([]int, 0), make([]int, 0)
+		for _, standing := range standings {
+			ratings = append(ratings, userRatings[standing.UserID])
+			ranks = append(ranks, standing.Rank)
+		}
+		deltas := CalculateRatingDeltas(ratings, ranks)
+		// 保存积分结算结果
+		for i, standing := range standings {
+			history := model.RatingHistory{
+				UserID:    standing.UserID,
+				ContestID: contest.ID,
+				Rank:      standing.Rank,
+				OldRating: ratings[i],
+				NewRating: ratings[i] + deltas[i]}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.User{}).Where("id = ?", standing.UserID).Update("rating", history.NewRating).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 数据库操作
+
+// GetUserRatingHistory 获取用户的积分变化历史，按时间升序排列
+func GetUserRatingHistory(uid uint64) (histories []model.RatingHistory) {
+	histories = make([]model.RatingHistory, 0)
+	global.DB.Where("user_id = ?", uid).Order("created_time asc").Find(&histories)
+	return
+}
+
+// GetContestNames 批量获取比赛名称
+func GetContestNames(contestIDs []uint64) map[uint64]string {
+	contests := make([]model.Contest, 0)
+	if len(contestIDs) > 0 {
+		global.DB.Select("id", "name").Where("id IN ?", contestIDs).Find(&contests)
+	}
+	names := make(map[uint64]string)
+	for _, contest := range contests {
+		names[contest.ID] = contest.Name
+	}
+	return names
+}
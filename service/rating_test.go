@@ -0,0 +1,54 @@
+package service
+
+import "testing"
+
+func TestCalculateRatingDeltas(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratings []int
+		ranks   []int
+	}{
+		{"单人", []int{1500}, []int{1}},
+		{"同分", []int{1500, 1500, 1500, 1500}, []int{1, 2, 3, 4}},
+		{"强者垫底", []int{2400, 1200, 1200}, []int{3, 1, 2}},
+		{"并列名次", []int{1600, 1500, 1400}, []int{1, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deltas := CalculateRatingDeltas(tt.ratings, tt.ranks)
+			if len(deltas) != len(tt.ratings) {
+				t.Fatalf("len(deltas) = %d, want %d", len(deltas), len(tt.ratings))
+			}
+			sum := 0
+			for _, delta := range deltas {
+				sum += delta
+			}
+			if sum > 0 {
+				t.Errorf("sum of deltas = %d, want <= 0", sum)
+			}
+		})
+	}
+}
+
+func TestCalculateRatingDeltasOrder(t *testing.T) {
+	deltas := CalculateRatingDeltas([]int{1500, 1500, 1500, 1500}, []int{1, 2, 3, 4})
+	for i := 1; i < len(deltas); i++ {
+		if deltas[i] > deltas[i-1] {
+			t.Errorf("deltas = %v, want non-increasing for equal ratings", deltas)
+		}
+	}
+	if deltas[0] <= 0 || deltas[3] >= 0 {
+		t.Errorf("deltas = %v, want winner gains and loser loses", deltas)
+	}
+	// 高分选手垫底时应大幅扣分，低分选手获胜时应加分
+	deltas = CalculateRatingDeltas([]int{2400, 1200, 1200}, []int{3, 1, 2})
+	if deltas[0] >= 0 || deltas[1] <= 0 {
+		t.Errorf("deltas = %v, want upset to move ratings", deltas)
+	}
+}
+
+func TestCalculateRatingDeltasEmpty(t *testing.T) {
+	if deltas := CalculateRatingDeltas(nil, nil); len(deltas) != 0 {
+		t.Errorf("deltas = %v, want empty", deltas)
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"gorm.io/gorm/clause"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 搜索文档的类型
+const (
+	SearchTypeProblem  = 1
+	SearchTypeTutorial = 2
+	SearchTypePost     = 3
+	SearchTypeLesson   = 4
+)
+
+// 权限过滤相关的参数
+const (
+	searchBatchSize = 100 // 权限过滤时每次从搜索引擎读取的结果数
+	searchMaxTotal  = 100 // 统计结果总数时至多统计的结果数，请求的页超出该范围时统计到该页为止
+	searchMinLength = 2   // ngram分词器的分词长度，短于该长度的关键字无法使用全文索引
+)
+
+// SearchEngine 全文搜索引擎，负责维护索引文档并按相关度检索，不负责权限过滤
+type SearchEngine interface {
+	// Index 新增或更新一个文档的索引
+	Index(doc model.SearchDocument) error
+	// Remove 删除一个文档的索引
+	Remove(docType int, targetID uint64) error
+	// Search 检索组织orgIDs中与关键字相关的文档，按相关度降序排列，跳过前offset个结果，types为空表示不限类型
+	Search(keyWord string, types []int, orgIDs []uint64, offset int, limit int) ([]SearchHit, error)
+}
+
+// SearchHit 搜索引擎返回的一条结果
+type SearchHit struct {
+	model.SearchDocument
+	Score float64
+}
+
+// mysqlSearchEngine 基于MySQL FULLTEXT索引的搜索引擎，使用ngram分词器以支持中文；
+// ngram默认按两个字符分词，单个字符的关键字无法命中全文索引，此时退化为LIKE匹配，按更新时间降序排列
+type mysqlSearchEngine struct{}
+
+func (mysqlSearchEngine) Index(doc model.SearchDocument) error {
+	return global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "type"}, {Name: "target_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"org_id", "title", "content", "updated_at"}),
+	}).Create(&doc).Error
+}
+
+func (mysqlSearchEngine) Remove(docType int, targetID uint64) error {
+	return global.DB.Where("type = ? AND target_id = ?", docType, targetID).Delete(&model.SearchDocument{}).Error
+}
+
+func (mysqlSearchEngine) Search(keyWord string, types []int, orgIDs []uint64, offset int, limit int) (hits []SearchHit, err error) {
+	hits = make([]SearchHit, 0)
+	if len(orgIDs) == 0 {
+		return hits, nil
+	}
+	db := global.DB.Model(&model.SearchDocument{}).Where("org_id IN ?", orgIDs)
+	if len(types) > 0 {
+		db = db.Where("type IN ?", types)
+	}
+	if utf8.RuneCountInString(keyWord) < searchMinLength {
+		pattern := "%" + likeEscaper.Replace(keyWord) + "%"
+		db = db.Select("*, 0 AS score").Where("title LIKE ? OR content LIKE ?", pattern, pattern).Order("updated_at desc, id asc")
+	} else {
+		match := "MATCH(title, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		db = db.Select("*, "+match+" AS score", keyWord).Where(match, keyWord).Order("score desc, id asc")
+	}
+	err = db.Offset(offset).Limit(limit).Scan(&hits).Error
+	return hits, err
+}
+
+// likeEscaper 转义LIKE模式中的通配符
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// searchEngine 当前使用的搜索引擎
+var searchEngine SearchEngine = mysqlSearchEngine{}
+
+// Helper
+
+// IndexProblem 为题目建立索引，索引内容为当前版本的题目描述
+func IndexProblem(problem model.Problem) {
+	path := filepath.Join(global.VP.GetString("problem_path"), GetProblemFileFolder(problem.ID, problem.Version), "description")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		global.LOG.Warn("IndexProblem: read problem description error: ", err)
+	}
+	indexDocument(model.SearchDocument{Type: SearchTypeProblem, TargetID: problem.ID, OrgID: problem.OrgID, Title: problem.Name, Content: string(content)})
+}
+
+// IndexTutorial 为教程建立索引，索引内容为当前版本的教程文件
+func IndexTutorial(tutorial model.Tutorial) {
+	content, err := ReadTutorialVersion(tutorial.ID, tutorial.Version)
+	if err != nil {
+		global.LOG.Warn("IndexTutorial: read tutorial file error: ", err)
+	}
+	indexDocument(model.SearchDocument{Type: SearchTypeTutorial, TargetID: tutorial.ID, OrgID: tutorial.OrgID, Title: tutorial.Name, Content: content})
+}
+
+// IndexLesson 为教程课时建立索引，索引内容为当前版本的课时文件
+func IndexLesson(lesson model.TutorialLesson, tutorial model.Tutorial) {
+	content, err := os.ReadFile(filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialLessonFileName(lesson)))
+	if err != nil {
+		global.LOG.Warn("IndexLesson: read lesson file error: ", err)
+	}
+	indexDocument(model.SearchDocument{Type: SearchTypeLesson, TargetID: lesson.ID, OrgID: tutorial.OrgID, Title: lesson.Title, Content: string(content)})
+}
+
+// IndexPost 为帖子建立索引
+func IndexPost(post model.Post) {
+	indexDocument(model.SearchDocument{Type: SearchTypePost, TargetID: post.ID, OrgID: post.OrgID, Title: post.Title, Content: post.Content})
+}
+
+// RemoveSearchDocument 删除题目、教程、课时或帖子的索引
+func RemoveSearchDocument(docType int, targetID uint64) {
+	if err := searchEngine.Remove(docType, targetID); err != nil {
+		global.LOG.Warn("RemoveSearchDocument: remove search document error: ", err)
+	}
+}
+
+// indexDocument 索引失败不影响原有操作，仅记录日志，索引会在下次重建时修复
+func indexDocument(doc model.SearchDocument) {
+	if err := searchEngine.Index(doc); err != nil {
+		global.LOG.Warn("indexDocument: index search document error: ", err)
+	}
+}
+
+// RemoveLessonDocuments 删除一组课时的索引，用于删除教程或章节时清理其中的课时
+func RemoveLessonDocuments(lessons []model.TutorialLesson) {
+	for _, lesson := range lessons {
+		RemoveSearchDocument(SearchTypeLesson, lesson.ID)
+	}
+}
+
+// RebuildSearchIndex 为所有题目、教程、课时与帖子重建索引，由定时任务在服务器启动时调用
+func RebuildSearchIndex() {
+	for _, problem := range QueryAllProblems() {
+		IndexProblem(problem)
+	}
+	for _, tutorial := range GetAllTutorials() {
+		IndexTutorial(tutorial)
+		for _, lesson := range GetTutorialLessons(tutorial.ID) {
+			IndexLesson(lesson, tutorial)
+		}
+	}
+	posts := make([]model.Post, 0)
+	global.DB.Find(&posts)
+	for _, post := range posts {
+		IndexPost(post)
+	}
+}
+
+// searchPermission 一次搜索中的权限过滤器，批量读取结果对应的对象，并缓存用户的组织与可读题目
+type searchPermission struct {
+	c           *gin.Context
+	uid         uint64
+	orgs        map[uint64]bool // 用户所在的组织，值为是否是管理员
+	problems    map[uint64]bool // 用户可读的题目，首次需要时加载
+	discussions map[uint64]bool // 题目讨论是否对用户开放，键为题目ID
+	solutions   map[uint64]bool // 题目的题解是否对用户可见，键为题目ID
+}
+
+func newSearchPermission(c *gin.Context) *searchPermission {
+	user := utils.SolveUser(c)
+	p := &searchPermission{
+		c:           c,
+		uid:         user.ID,
+		orgs:        make(map[uint64]bool),
+		discussions: make(map[uint64]bool),
+		solutions:   make(map[uint64]bool)}
+	for _, invitation := range GetUserOrganization(user.ID) {
+		p.orgs[invitation.OrgID] = invitation.IsAdmin
+	}
+	return p
+}
+
+func (p *searchPermission) canReadProblem(id uint64) bool {
+	if p.problems == nil {
+		p.problems = make(map[uint64]bool)
+		for _, problem := range GetReadableProblems(p.c) {
+			p.problems[problem.ID] = true
+		}
+	}
+	return p.problems[id]
+}
+
+func (p *searchPermission) canReadTutorial(tutorial model.Tutorial) bool {
+	isAdmin, isMember := p.orgs[tutorial.OrgID]
+	return judgeReadable(tutorial.Readable, tutorial.CreatorID, p.uid, isMember, isAdmin)
+}
+
+func (p *searchPermission) canReadPost(post model.Post) bool {
+	if post.ProblemID == 0 {
+		_, isMember := p.orgs[post.OrgID]
+		return isMember
+	}
+	if !p.canReadProblem(post.ProblemID) {
+		return false
+	}
+	if _, ok := p.discussions[post.ProblemID]; !ok {
+		problem, _ := GetProblemByID(post.ProblemID)
+		p.discussions[post.ProblemID] = !IsDiscussionClosed(problem, p.uid)
+		p.solutions[post.ProblemID] = CanViewSolution(problem, p.uid)
+	}
+	return p.discussions[post.ProblemID] && (!post.IsSolution || post.CreatorID == p.uid || p.solutions[post.ProblemID])
+}
+
+// filter 批量读取一批结果对应的对象，返回用户可读的结果，以及课时结果对应的教程ID
+func (p *searchPermission) filter(rawHits []SearchHit) (hits []SearchHit, lessonTutorials map[uint64]uint64) {
+	ids := make(map[int][]uint64)
+	for _, hit := range rawHits {
+		ids[hit.Type] = append(ids[hit.Type], hit.TargetID)
+	}
+	problems, tutorials, posts, lessons := make(map[uint64]bool), make(map[uint64]model.Tutorial), make(map[uint64]model.Post), make(map[uint64]model.TutorialLesson)
+	if len(ids[SearchTypeProblem]) > 0 {
+		existing := make([]uint64, 0)
+		global.DB.Model(&model.Problem{}).Where("id IN ?", ids[SearchTypeProblem]).Pluck("id", &existing)
+		for _, id := range existing {
+			problems[id] = true
+		}
+	}
+	if len(ids[SearchTypeLesson]) > 0 {
+		rows := make([]model.TutorialLesson, 0)
+		global.DB.Where("id IN ?", ids[SearchTypeLesson]).Find(&rows)
+		for _, lesson := range rows {
+			lessons[lesson.ID] = lesson
+			ids[SearchTypeTutorial] = append(ids[SearchTypeTutorial], lesson.TutorialID)
+		}
+	}
+	if len(ids[SearchTypeTutorial]) > 0 {
+		rows := make([]model.Tutorial, 0)
+		global.DB.Where("id IN ?", ids[SearchTypeTutorial]).Find(&rows)
+		for _, tutorial := range rows {
+			tutorials[tutorial.ID] = tutorial
+		}
+	}
+	if len(ids[SearchTypePost]) > 0 {
+		rows := make([]model.Post, 0)
+		global.DB.Where("id IN ?", ids[SearchTypePost]).Find(&rows)
+		for _, post := range rows {
+			posts[post.ID] = post
+		}
+	}
+	hits, lessonTutorials = make([]SearchHit, 0), make(map[uint64]uint64)
+	for _, hit := range rawHits {
+		readable := false
+		switch hit.Type {
+		case SearchTypeProblem:
+			readable = problems[hit.TargetID] && p.canReadProblem(hit.TargetID)
+		case SearchTypeTutorial:
+			tutorial, ok := tutorials[hit.TargetID]
+			readable = ok && p.canReadTutorial(tutorial)
+		case SearchTypeLesson:
+			lesson, ok := lessons[hit.TargetID]
+			tutorial, found := tutorials[lesson.TutorialID]
+			readable = ok && found && p.canReadTutorial(tutorial)
+			lessonTutorials[lesson.ID] = lesson.TutorialID
+		case SearchTypePost:
+			post, ok := posts[hit.TargetID]
+			readable = ok && p.canReadPost(post)
+		}
+		if readable {
+			hits = append(hits, hit)
+		}
+	}
+	return
+}
+
+// SearchReadable 全文搜索当前用户所在组织中用户可读的题目、教程、课时与帖子，分批读取搜索结果并过滤权限，
+// 返回过滤后第page页的结果以及过滤后的结果总数；结果总数至多统计到max(searchMaxTotal, 第page页末尾)，达到时hasMore为true
+func SearchReadable(keyWord string, types []int, page int, size int, c *gin.Context) (hits []model.SearchHitT, total int, hasMore bool, err error) {
+	hits = make([]model.SearchHitT, 0)
+	p := newSearchPermission(c)
+	orgIDs := make([]uint64, 0)
+	for id := range p.orgs {
+		orgIDs = append(orgIDs, id)
+	}
+	start := (page - 1) * size
+	limit := start + size
+	if limit < searchMaxTotal {
+		limit = searchMaxTotal
+	}
+	for offset := 0; ; offset += searchBatchSize {
+		rawHits, err := searchEngine.Search(keyWord, types, orgIDs, offset, searchBatchSize)
+		if err != nil {
+			return hits, 0, false, err
+		}
+		readable, lessonTutorials := p.filter(rawHits)
+		for _, hit := range readable {
+			if total >= start && total < start+size {
+				hits = append(hits, model.SearchHitT{
+					Type:       hit.Type,
+					ID:         hit.TargetID,
+					TutorialID: lessonTutorials[hit.TargetID],
+					Title:      hit.Title,
+					Snippet:    makeSnippet(hit.Content, keyWord, 80),
+					Score:      hit.Score})
+			}
+			if total++; total >= limit {
+				return hits, total, true, nil
+			}
+		}
+		if len(rawHits) < searchBatchSize {
+			return hits, total, false, nil
+		}
+	}
+}
+
+// makeSnippet 截取内容中关键字附近至多size个字符的片段，找不到关键字时截取开头
+func makeSnippet(content string, keyWord string, size int) string {
+	runes := []rune(content)
+	start := 0
+	if index := indexRunesFold(runes, []rune(keyWord)); index >= 0 {
+		start = index - size/4
+	}
+	if start+size > len(runes) {
+		start = len(runes) - size
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + size
+	if end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[start:end])
+}
+
+// indexRunesFold 在字符序列中忽略大小写查找子序列，返回第一次出现的字符位置，找不到时返回-1
+func indexRunesFold(runes []rune, sub []rune) int {
+	for i := 0; i+len(sub) <= len(runes); i++ {
+		matched := true
+		for j, r := range sub {
+			if unicode.ToLower(runes[i+j]) != unicode.ToLower(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
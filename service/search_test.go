@@ -0,0 +1,30 @@
+package service
+
+import "testing"
+
+func TestMakeSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keyWord string
+		size    int
+		want    string
+	}{
+		{"找不到关键字时截取开头", "abcdefghij", "xyz", 4, "abcd"},
+		{"关键字前保留四分之一的上下文", "0123456789abcdefghij", "abc", 8, "89abcdef"},
+		{"忽略大小写", "0123456789ABCDEFGHIJ", "abc", 8, "89ABCDEF"},
+		{"中文按字符截取", "零一二三四五六七八九", "五", 4, "四五六七"},
+		{"关键字靠近末尾时向前补足", "0123456789", "9", 4, "6789"},
+		{"内容短于片段长度", "短文本", "文", 80, "短文本"},
+		// 小写后字节长度改变的字符不影响定位
+		{"大小写转换改变字节长度", "ȺȺȺȺkey", "KEY", 4, "Ⱥkey"},
+		{"空内容", "", "key", 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := makeSnippet(tt.content, tt.keyWord, tt.size); got != tt.want {
+				t.Errorf("makeSnippet(%q, %q, %d) = %q, want %q", tt.content, tt.keyWord, tt.size, got, tt.want)
+			}
+		})
+	}
+}
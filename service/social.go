@@ -20,6 +20,12 @@ func IsUserInThisOrganization(uid uint64, orgID uint64) (ok bool, err error) {
 	return !notFound, nil
 }
 
+// IsOrganizationAdmin 判断用户是否为该组织的管理员
+func IsOrganizationAdmin(uid uint64, orgID uint64) bool {
+	invitation, notFound := GetInvitationByUserOrg(uid, orgID)
+	return !notFound && invitation.IsAdmin
+}
+
 // 数据库操作
 
 // GetOrganizationByID 根据组织 ID 查询某个组织
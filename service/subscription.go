@@ -0,0 +1,303 @@
+package service
+
+import (
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 订阅的类型
+const (
+	SubscriptionTypePost  = 1
+	SubscriptionTypeBoard = 2
+)
+
+// 摘要邮件的发送频率
+const (
+	DigestFrequencyImmediate = 1
+	DigestFrequencyDaily     = 2
+	DigestFrequencyWeekly    = 3
+)
+
+// DigestFrequencyDefault 用户第一次订阅时的默认发送频率
+const DigestFrequencyDefault = DigestFrequencyDaily
+
+// digestPeriods 每日与每周摘要两次发送之间的间隔
+var digestPeriods = map[int]time.Duration{
+	DigestFrequencyDaily:  24 * time.Hour,
+	DigestFrequencyWeekly: 7 * 24 * time.Hour,
+}
+
+// boardNames 论坛板块的名称
+var boardNames = []string{"公告板块", "划水板块", "讨论板块"}
+
+// digestItem 邮件中的一条订阅动态，CommentID为0表示新帖子
+type digestItem struct {
+	Subscription model.Subscription
+	Post         model.Post
+	CommentID    uint64
+	ActorID      uint64
+	Content      string
+}
+
+// Helper
+
+// IsValidDigestFrequency 判断摘要邮件的发送频率是否合法
+func IsValidDigestFrequency(frequency int) bool {
+	return frequency == DigestFrequencyImmediate || frequency == DigestFrequencyDaily || frequency == DigestFrequencyWeekly
+}
+
+// IsValidBoard 判断论坛板块是否存在
+func IsValidBoard(postType uint64) bool {
+	return postType < uint64(len(boardNames))
+}
+
+// GetBoardName 获取论坛板块的名称
+func GetBoardName(postType int) string {
+	if postType < 0 || postType >= len(boardNames) {
+		return "未知板块"
+	}
+	return boardNames[postType]
+}
+
+// BuildSubscriptions 批量获取订阅帖子的标题，生成订阅列表
+func BuildSubscriptions(subscriptions []model.Subscription) []model.SubscriptionT {
+	postIDs := make([]uint64, 0)
+	for _, subscription := range subscriptions {
+		if subscription.TargetType == SubscriptionTypePost {
+			postIDs = append(postIDs, subscription.TargetID)
+		}
+	}
+	posts := make(map[uint64]model.Post)
+	tmp := make([]model.Post, 0)
+	if len(postIDs) > 0 {
+		global.DB.Where("id IN ?", postIDs).Find(&tmp)
+	}
+	for _, post := range tmp {
+		posts[post.ID] = post
+	}
+	res := make([]model.SubscriptionT, 0)
+	for _, subscription := range subscriptions {
+		name := GetBoardName(int(subscription.TargetID))
+		if subscription.TargetType == SubscriptionTypePost {
+			name = posts[subscription.TargetID].Title
+		}
+		res = append(res, model.SubscriptionT{
+			ID:         subscription.ID,
+			OrgID:      subscription.OrgID,
+			TargetType: subscription.TargetType,
+			TargetID:   subscription.TargetID,
+			TargetName: name,
+			CreatedAt:  subscription.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+// GetUnsubscribeURL 获取无需登录即可退订的链接
+func GetUnsubscribeURL(subscription model.Subscription) string {
+	return strings.TrimSuffix(global.VP.GetString("server.url"), "/") + "/api/v1/notifications/unsubscribe?id=" +
+		strconv.FormatUint(subscription.ID, 10) + "&token=" + utils.GenerateUnsubscribeToken(subscription.ID)
+}
+
+// sendDigestMail 将若干条订阅动态汇总为一封邮件发送给用户
+func sendDigestMail(user model.User, subject string, items []digestItem) error {
+	actorIDs := make([]uint64, 0)
+	for _, item := range items {
+		actorIDs = append(actorIDs, item.ActorID)
+	}
+	actors := GetUsersByIDs(actorIDs)
+	var body strings.Builder
+	body.WriteString("<p>" + html.EscapeString(user.Name) + "，您好！您订阅的论坛内容有以下新动态：</p><ul>")
+	for _, item := range items {
+		action := "发表了新帖子"
+		if item.CommentID != 0 {
+			action = "发表了新评论"
+		}
+		body.WriteString("<li><b>" + html.EscapeString(actors[item.ActorID].Name) + "</b> 在「" +
+			html.EscapeString(item.Post.Title) + "」中" + action + "：" + html.EscapeString(TruncateContent(item.Content)) +
+			` <a href="` + html.EscapeString(GetUnsubscribeURL(item.Subscription)) + `">退订</a></li>`)
+	}
+	body.WriteString("</ul><p>如需调整邮件的发送频率，请前往PhoeniX学习平台的通知设置。</p>")
+	return utils.SendMail([]string{user.Email}, subject, body.String())
+}
+
+// isSubscriptionReadable 判断订阅者当前能否查看订阅中的帖子，题目讨论中的帖子不要求组织成员身份
+func isSubscriptionReadable(uid uint64, subscription model.Subscription, post model.Post) bool {
+	if post.ProblemID == 0 {
+		ok, err := IsUserInThisOrganization(uid, subscription.OrgID)
+		return ok && err == nil
+	}
+	problem, notFound := GetProblemByID(post.ProblemID)
+	if notFound || !judgeProblemReadable(problem, uid) || IsDiscussionClosed(problem, uid) {
+		return false
+	}
+	return !post.IsSolution || post.CreatorID == uid || CanViewSolution(problem, uid)
+}
+
+// 数据库操作
+
+// GetSubscriptionByID 通过ID获取订阅
+func GetSubscriptionByID(id uint64) (subscription model.Subscription, notFound bool) {
+	if err := global.DB.First(&subscription, id).Error; err != nil {
+		return subscription, true
+	}
+	return subscription, false
+}
+
+// GetSubscriptions 获取用户的所有订阅，按时间降序排列
+func GetSubscriptions(uid uint64) (subscriptions []model.Subscription) {
+	global.DB.Where("user_id = ?", uid).Order("id desc").Find(&subscriptions)
+	return
+}
+
+// Subscribe 订阅帖子或板块，已订阅时返回原有的订阅
+func Subscribe(subscription model.Subscription) (model.Subscription, error) {
+	GetDigestSetting(subscription.UserID)
+	err := global.DB.Where(model.Subscription{
+		UserID:     subscription.UserID,
+		OrgID:      subscription.OrgID,
+		TargetType: subscription.TargetType,
+		TargetID:   subscription.TargetID}).FirstOrCreate(&subscription).Error
+	return subscription, err
+}
+
+// Unsubscribe 取消订阅
+func Unsubscribe(subscription model.Subscription) error {
+	return global.DB.Delete(&subscription).Error
+}
+
+// getMaxForumIDs 获取当前最大的帖子ID与评论ID，包括被删除的帖子与评论
+func getMaxForumIDs() (maxPostID uint64, maxCommentID uint64) {
+	global.DB.Unscoped().Model(&model.Post{}).Select("COALESCE(MAX(id), 0)").Scan(&maxPostID)
+	global.DB.Unscoped().Model(&model.Comment{}).Select("COALESCE(MAX(id), 0)").Scan(&maxCommentID)
+	return
+}
+
+// GetDigestSetting 获取用户的摘要邮件设置，没有设置时以默认频率创建，并从当前的动态开始汇总
+func GetDigestSetting(uid uint64) (setting model.DigestSetting) {
+	maxPostID, maxCommentID := getMaxForumIDs()
+	err := global.DB.Where(model.DigestSetting{UserID: uid}).
+		Attrs(model.DigestSetting{Frequency: DigestFrequencyDefault, LastPostID: maxPostID, LastCommentID: maxCommentID}).
+		FirstOrCreate(&setting).Error
+	if err != nil {
+		global.LOG.Warn("GetDigestSetting: create digest setting error: ", err)
+	}
+	return
+}
+
+// UpdateDigestSetting 更新摘要邮件的发送频率，此前的动态不再汇总，以免与即时邮件重复
+func UpdateDigestSetting(uid uint64, frequency int) error {
+	setting := GetDigestSetting(uid)
+	maxPostID, maxCommentID := getMaxForumIDs()
+	return global.DB.Model(&setting).Updates(map[string]interface{}{
+		"frequency": frequency, "last_post_id": maxPostID, "last_comment_id": maxCommentID}).Error
+}
+
+// NotifySubscribers 向选择即时发送的订阅者发送新帖子或新评论的邮件，comment为空表示新帖子，邮件在后台发送
+func NotifySubscribers(post model.Post, comment *model.Comment) {
+	// 题目讨论中的新帖子不属于任何板块
+	if comment == nil && post.ProblemID != 0 {
+		return
+	}
+	item := digestItem{Post: post, ActorID: post.CreatorID, Content: post.Content}
+	db := global.DB.Where("target_type = ? AND target_id = ? AND org_id = ?", SubscriptionTypeBoard, post.Type, post.OrgID)
+	if comment != nil {
+		item.CommentID, item.ActorID, item.Content = comment.ID, comment.CreatorID, comment.Content
+		db = global.DB.Where("target_type = ? AND target_id = ?", SubscriptionTypePost, post.ID)
+	}
+	subscriptions := make([]model.Subscription, 0)
+	db.Where("user_id <> ?", item.ActorID).Find(&subscriptions)
+	if len(subscriptions) == 0 {
+		return
+	}
+	userIDs := make([]uint64, 0)
+	for _, subscription := range subscriptions {
+		userIDs = append(userIDs, subscription.UserID)
+	}
+	immediate := make(map[uint64]bool)
+	settings := make([]model.DigestSetting, 0)
+	global.DB.Where("user_id IN ? AND frequency = ?", userIDs, DigestFrequencyImmediate).Find(&settings)
+	for _, setting := range settings {
+		immediate[setting.UserID] = true
+	}
+	users := GetUsersByIDs(userIDs)
+	for _, subscription := range subscriptions {
+		if !immediate[subscription.UserID] {
+			continue
+		}
+		if !isSubscriptionReadable(subscription.UserID, subscription, post) {
+			continue
+		}
+		user, item := users[subscription.UserID], item
+		item.Subscription = subscription
+		go func() {
+			if err := sendDigestMail(user, "「"+post.Title+"」有新动态", []digestItem{item}); err != nil {
+				global.LOG.Warn("NotifySubscribers: send mail error: ", err)
+			}
+		}()
+	}
+}
+
+// collectDigest 收集用户订阅内容中尚未汇总的新帖子与新评论，不包括用户自己发表的内容
+func collectDigest(setting model.DigestSetting, maxPostID uint64, maxCommentID uint64) []digestItem {
+	items := make([]digestItem, 0)
+	for _, subscription := range GetSubscriptions(setting.UserID) {
+		if subscription.TargetType == SubscriptionTypeBoard {
+			if ok, err := IsUserInThisOrganization(setting.UserID, subscription.OrgID); !ok || err != nil {
+				continue
+			}
+			posts := make([]model.Post, 0)
+			global.DB.Where("org_id = ? AND type = ? AND problem_id = 0 AND creator_id <> ? AND id > ? AND id <= ?",
+				subscription.OrgID, subscription.TargetID, setting.UserID, setting.LastPostID, maxPostID).Order("id").Find(&posts)
+			for _, post := range posts {
+				items = append(items, digestItem{Subscription: subscription, Post: post, ActorID: post.CreatorID, Content: post.Content})
+			}
+			continue
+		}
+		post, notFound := GetPostByID(subscription.TargetID)
+		if notFound || !isSubscriptionReadable(setting.UserID, subscription, post) {
+			continue
+		}
+		comments := make([]model.Comment, 0)
+		global.DB.Where("post_id = ? AND creator_id <> ? AND id > ? AND id <= ?",
+			post.ID, setting.UserID, setting.LastCommentID, maxCommentID).Order("id").Find(&comments)
+		for _, comment := range comments {
+			items = append(items, digestItem{Subscription: subscription, Post: post, CommentID: comment.ID, ActorID: comment.CreatorID, Content: comment.Content})
+		}
+	}
+	return items
+}
+
+// SendForumDigests 向到期的用户发送每日或每周摘要邮件，由定时任务调用
+func SendForumDigests() {
+	now := time.Now()
+	maxPostID, maxCommentID := getMaxForumIDs()
+	settings := make([]model.DigestSetting, 0)
+	global.DB.Where("frequency IN ?", []int{DigestFrequencyDaily, DigestFrequencyWeekly}).Find(&settings)
+	for _, setting := range settings {
+		// 定时任务每小时执行一次，留出少量余量以免执行时间的抖动使摘要推迟一小时
+		if setting.LastSentAt != nil && now.Sub(*setting.LastSentAt) < digestPeriods[setting.Frequency]-time.Minute {
+			continue
+		}
+		if items := collectDigest(setting, maxPostID, maxCommentID); len(items) > 0 {
+			user, notFound := GetUserByID(setting.UserID)
+			if notFound {
+				continue
+			}
+			subject := "PhoeniX论坛每日摘要"
+			if setting.Frequency == DigestFrequencyWeekly {
+				subject = "PhoeniX论坛每周摘要"
+			}
+			if err := sendDigestMail(user, subject, items); err != nil {
+				global.LOG.Warn("SendForumDigests: send mail error: ", err)
+				continue
+			}
+		}
+		global.DB.Model(&setting).Updates(map[string]interface{}{
+			"last_post_id": maxPostID, "last_comment_id": maxCommentID, "last_sent_at": now})
+	}
+}
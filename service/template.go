@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/phoenix-next/phoenix-server/global"
+	"github.com/phoenix-next/phoenix-server/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ContestTemplateNameMaxLength 比赛模板名称的最大字符数
+const ContestTemplateNameMaxLength = 24
+
+// ErrTemplatePoolEmpty 根据比赛模板创建比赛时，题目池中没有仍然存在的题目
+var ErrTemplatePoolEmpty = errors.New("contest template problem pool is empty")
+
+// Helper
+
+// ParseClock 将15:04格式的时刻解析为距离当天零点的分钟数
+func ParseClock(clock string) (minute int, ok bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// FormatClock 将距离当天零点的分钟数格式化为15:04格式的时刻
+func FormatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60%24, minute%60)
+}
+
+// GetNextTemplateTime 获取比赛模板在after之后举行的下一场比赛的开始时间
+func GetNextTemplateTime(template model.ContestTemplate, after time.Time) time.Time {
+	year, month, day := after.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, after.Location())
+	date = date.AddDate(0, 0, (template.Weekday-int(date.Weekday())+7)%7)
+	next := date.Add(time.Duration(template.StartMinute) * time.Minute)
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// CreateContestFromTemplate 根据比赛模板创建一场比赛，从题目池的NextIndex处开始按顺序轮换选取题目
+func CreateContestFromTemplate(template model.ContestTemplate, startTime time.Time) error {
+	// 从题目池中选取仍然存在的题目
+	pool := make([]uint64, 0)
+	for _, problem := range GetContestTemplateProblems(template.ID) {
+		if _, notFound := GetProblemByID(problem.ProblemID); !notFound {
+			pool = append(pool, problem.ProblemID)
+		}
+	}
+	// 题目池中的题目均已被删除时，不创建没有题目的比赛
+	if len(pool) == 0 {
+		return ErrTemplatePoolEmpty
+	}
+	count := template.ProblemCount
+	if count > len(pool) {
+		count = len(pool)
+	}
+	problemIDs := make([]uint64, 0)
+	for i := 0; i < count; i++ {
+		problemIDs = append(problemIDs, pool[(template.NextIndex+i)%len(pool)])
+	}
+	problems, _ := BuildContestProblems(problemIDs, nil)
+	// 在同一事务中创建比赛并更新模板的轮换状态，避免下次定时任务重复创建比赛
+	contest := model.Contest{
+		OrgID:     template.OrgID,
+		Name:      fmt.Sprintf("%s #%d", template.Name, template.Count+1),
+		Profile:   template.Profile,
+		Readable:  template.Readable,
+		IsRated:   template.IsRated,
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Duration(template.Duration) * time.Minute)}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := createContest(tx, &contest, problems); err != nil {
+			return err
+		}
+		return tx.Model(&template).Updates(map[string]interface{}{
+			"next_index":      (template.NextIndex + count) % len(pool),
+			"count":           template.Count + 1,
+			"last_start_time": startTime}).Error
+	})
+}
+
+// CreateUpcomingContests 为每个比赛模板创建下一场即将举行的比赛，若已创建则跳过，由定时任务调用
+func CreateUpcomingContests() {
+	now := time.Now()
+	for _, template := range GetAllContestTemplates() {
+		next := GetNextTemplateTime(template, now)
+		if template.LastStartTime != nil && !template.LastStartTime.Before(next) {
+			continue
+		}
+		if err := CreateContestFromTemplate(template, next); err != nil {
+			global.LOG.Warn("CreateUpcomingContests: create contest from template error: ", err)
+		}
+	}
+}
+
+// 数据库操作
+
+// GetContestTemplateByID 根据ID获取比赛模板
+func GetContestTemplateByID(ID uint64) (template model.ContestTemplate, notFound bool) {
+	err := global.DB.First(&template, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return template, true
+	} else if err != nil {
+		global.LOG.Panic("GetContestTemplateByID: search error")
+		return template, true
+	}
+	return template, false
+}
+
+// GetAllContestTemplates 获取所有比赛模板
+func GetAllContestTemplates() (templates []model.ContestTemplate) {
+	templates = make([]model.ContestTemplate, 0)
+	global.DB.Find(&templates)
+	return
+}
+
+// GetOrganizationContestTemplates 获取一个组织的所有比赛模板
+func GetOrganizationContestTemplates(orgID uint64) (templates []model.ContestTemplate) {
+	templates = make([]model.ContestTemplate, 0)
+	global.DB.Where("org_id = ?", orgID).Find(&templates)
+	return
+}
+
+// GetContestTemplateProblems 获取比赛模板的题目池，按位置排列
+func GetContestTemplateProblems(templateID uint64) (problems []model.ContestTemplateProblem) {
+	problems = make([]model.ContestTemplateProblem, 0)
+	global.DB.Where("template_id = ?", templateID).Order("position asc").Find(&problems)
+	return
+}
+
+// CreateContestTemplate 在一个事务中创建比赛模板及其题目池
+func CreateContestTemplate(template *model.ContestTemplate, problemIDs []uint64) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(template).Error; err != nil {
+			return err
+		}
+		for i, problemID := range problemIDs {
+			problem := model.ContestTemplateProblem{TemplateID: template.ID, ProblemID: problemID, Position: i}
+			if err := tx.Create(&problem).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteContestTemplate 在一个事务中删除比赛模板及其题目池，已创建的比赛不受影响
+func DeleteContestTemplate(template *model.ContestTemplate) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", template.ID).Delete(&model.ContestTemplateProblem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(template).Error
+	})
+}
@@ -1,20 +1,423 @@
 package service
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"github.com/gin-gonic/gin"
 	"github.com/phoenix-next/phoenix-server/global"
 	"github.com/phoenix-next/phoenix-server/model"
+	"github.com/phoenix-next/phoenix-server/utils"
 	"gorm.io/gorm"
+	"mime/multipart"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// TutorialLockDuration 教程编辑锁的有效时长，持有者需在过期前续期
+const TutorialLockDuration = 10 * time.Minute
+
+// TutorialBundleMaxSize 教程包解压后的最大总大小
+const TutorialBundleMaxSize = 64 << 20
+
+// TutorialBundleMaxEntries 教程包中的最大文件与文件夹数量
+const TutorialBundleMaxEntries = 1000
+
+// 教程包中允许的文件类型，只允许markdown文档与图片，防止上传的HTML或脚本在服务器域名下执行
+var (
+	tutorialMarkdownExts = map[string]bool{".md": true, ".markdown": true}
+	tutorialImageExts    = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".bmp": true}
+)
+
+// TutorialNoteMaxLength 修改说明的最大字符数
+const TutorialNoteMaxLength = 255
+
+// TutorialTitleMaxLength 章节与课时标题的最大字符数
+const TutorialTitleMaxLength = 64
+
+// TutorialDiffMaxSize 参与版本比较的教程文件的最大大小
+const TutorialDiffMaxSize = 1 << 20
+
+// ErrTutorialVersionConflict 更新教程时，教程已被他人更新为新版本
+var ErrTutorialVersionConflict = errors.New("tutorial version conflict")
+
+// ErrTutorialNotDiffable 比较教程版本时，教程文件过大或不是文本文件
+var ErrTutorialNotDiffable = errors.New("tutorial file too large or not text")
+
 // Helper
 
 // GetTutorialFileName 获取教程对应的文件名称
 func GetTutorialFileName(tutorial model.Tutorial) string {
-	return strconv.Itoa(int(tutorial.ID)) + "_" + strconv.Itoa(tutorial.Version)
+	return GetTutorialVersionFileName(tutorial.ID, tutorial.Version)
+}
+
+// IsTutorialLockedByOthers 判断教程是否被其他用户持有未过期的编辑锁
+func IsTutorialLockedByOthers(tutorial model.Tutorial, uid uint64) bool {
+	return tutorial.LockerID != 0 && tutorial.LockerID != uid &&
+		tutorial.LockExpireTime != nil && time.Now().Before(*tutorial.LockExpireTime)
+}
+
+// GetTutorialLock 获取教程编辑锁的状态，已过期的编辑锁视为未锁定
+func GetTutorialLock(tutorial model.Tutorial) (lock model.TutorialLockT) {
+	if tutorial.LockerID == 0 || tutorial.LockExpireTime == nil || !time.Now().Before(*tutorial.LockExpireTime) {
+		return lock
+	}
+	locker, _ := GetUserByID(tutorial.LockerID)
+	return model.TutorialLockT{
+		IsLocked:   true,
+		LockerID:   tutorial.LockerID,
+		LockerName: locker.Name,
+		ExpireTime: tutorial.LockExpireTime.Format("2006-01-02 15:04:05")}
+}
+
+// GetTutorialVersionFileName 获取教程某个历史版本对应的文件名称
+func GetTutorialVersionFileName(tutorialID uint64, version int) string {
+	return strconv.FormatUint(tutorialID, 10) + "_" + strconv.Itoa(version)
+}
+
+// GetTutorialPaths 获取教程某个版本的下载路径，以及教程包的下载路径
+// 单个文件的版本保存为名为"ID_版本"的文件；教程包保存为"ID_版本.zip"，并解压到名为"ID_版本"的文件夹，
+// 因此主文档中的相对链接会相对该文件夹解析
+func GetTutorialPaths(tutorialID uint64, version int, mainFile string) (tutorialPath string, bundlePath string) {
+	name := "resource/tutorial/" + GetTutorialVersionFileName(tutorialID, version)
+	if mainFile == "" {
+		return name, ""
+	}
+	return name + "/" + mainFile, name + ".zip"
+}
+
+// getTutorialVersionPath 获取教程某个历史版本的文件路径，教程包为其主文档的路径
+func getTutorialVersionPath(tutorialID uint64, version int) string {
+	path := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, version))
+	if mainFile := GetTutorialMainFile(tutorialID, version); mainFile != "" {
+		path = filepath.Join(path, filepath.FromSlash(mainFile))
+	}
+	return path
+}
+
+// ReadTutorialVersion 读取教程某个历史版本的内容，教程包读取其主文档
+func ReadTutorialVersion(tutorialID uint64, version int) (string, error) {
+	data, err := os.ReadFile(getTutorialVersionPath(tutorialID, version))
+	return string(data), err
+}
+
+// ReadTutorialVersionText 读取教程某个历史版本的文本内容用于比较，文件过大或不是UTF-8文本时返回ErrTutorialNotDiffable
+func ReadTutorialVersionText(tutorialID uint64, version int) (string, error) {
+	path := getTutorialVersionPath(tutorialID, version)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() || info.Size() > TutorialDiffMaxSize {
+		return "", ErrTutorialNotDiffable
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", ErrTutorialNotDiffable
+	}
+	return string(data), nil
+}
+
+// SaveTutorialFile 保存上传的教程文件，zip文件作为教程包解压到版本文件夹中，并返回主文档的相对路径
+func SaveTutorialFile(c *gin.Context, file *multipart.FileHeader, tutorialID uint64, version int, mainFile string) (string, error) {
+	path := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, version))
+	if !strings.EqualFold(filepath.Ext(file.Filename), ".zip") {
+		return "", c.SaveUploadedFile(file, path)
+	}
+	if err := c.SaveUploadedFile(file, path+".zip"); err != nil {
+		return "", err
+	}
+	return extractTutorialBundle(tutorialID, version, mainFile)
+}
+
+// CopyTutorialVersion 将教程的from版本复制为to版本，返回to版本主文档的相对路径
+func CopyTutorialVersion(tutorialID uint64, from int, to int) (string, error) {
+	src := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, from))
+	dst := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, to))
+	mainFile := GetTutorialMainFile(tutorialID, from)
+	if mainFile == "" {
+		return "", utils.CopyFile(src, dst)
+	}
+	if err := utils.CopyFile(src+".zip", dst+".zip"); err != nil {
+		return "", err
+	}
+	return extractTutorialBundle(tutorialID, to, mainFile)
+}
+
+// RemoveTutorialFiles 删除教程某个版本的文件，用于保存失败时的清理
+func RemoveTutorialFiles(tutorialID uint64, version int) {
+	path := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, version))
+	_ = os.RemoveAll(path)
+	_ = os.Remove(path + ".zip")
+}
+
+// IsValidTutorialTitle 判断章节或课时标题是否非空且不超过最大长度
+func IsValidTutorialTitle(title string) bool {
+	return title != "" && utf8.RuneCountInString(title) <= TutorialTitleMaxLength
+}
+
+// IsTutorialMarkdownFile 判断文件是否为教程中的markdown文档
+func IsTutorialMarkdownFile(name string) bool {
+	return tutorialMarkdownExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// IsTutorialImageFile 判断文件是否为教程中的图片
+func IsTutorialImageFile(name string) bool {
+	return tutorialImageExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// IsTutorialBundleFile 判断文件是否为教程包中允许的文件类型
+func IsTutorialBundleFile(name string) bool {
+	return IsTutorialMarkdownFile(name) || IsTutorialImageFile(name)
+}
+
+// extractTutorialBundle 将教程包解压到版本文件夹中，并查找主文档，失败时删除已解压的文件
+func extractTutorialBundle(tutorialID uint64, version int, mainFile string) (string, error) {
+	path := filepath.Join(global.VP.GetString("tutorial_path"), GetTutorialVersionFileName(tutorialID, version))
+	if err := utils.Unzip(path+".zip", path, TutorialBundleMaxSize, TutorialBundleMaxEntries, IsTutorialBundleFile); err != nil {
+		RemoveTutorialFiles(tutorialID, version)
+		return "", err
+	}
+	mainFile, err := findBundleMainFile(path, mainFile)
+	if err != nil {
+		RemoveTutorialFiles(tutorialID, version)
+	}
+	return mainFile, err
+}
+
+// findBundleMainFile 在教程包文件夹中查找主文档，未指定时依次查找index.md、README.md与根目录下唯一的markdown文件
+func findBundleMainFile(dir string, mainFile string) (string, error) {
+	isFile := func(name string) bool {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		return err == nil && info.Mode().IsRegular()
+	}
+	if mainFile != "" {
+		if !utils.IsLocalPath(mainFile) || !isFile(mainFile) {
+			return "", errors.New("main file " + mainFile + " not found in bundle")
+		}
+		return filepath.ToSlash(filepath.Clean(mainFile)), nil
+	}
+	for _, name := range []string{"index.md", "README.md", "readme.md"} {
+		if isFile(name) {
+			return name, nil
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+	if len(candidates) != 1 {
+		return "", errors.New("main file not found in bundle")
+	}
+	return candidates[0], nil
+}
+
+// GetTutorialLessonFileName 获取教程课时对应的文件名称
+func GetTutorialLessonFileName(lesson model.TutorialLesson) string {
+	return "lesson_" + strconv.FormatUint(lesson.ID, 10) + "_" + strconv.Itoa(lesson.Version)
+}
+
+// GetTutorialChapterFromParam 通过路径参数获取教程章节，章节需属于路径中的教程
+func GetTutorialChapterFromParam(c *gin.Context) (chapter model.TutorialChapter, ok bool) {
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	cid, err2 := strconv.ParseUint(c.Param("chapterID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		global.LOG.Warn("GetTutorialChapterFromParam: id invalid")
+		return chapter, false
+	}
+	chapter, notFound := GetTutorialChapterByID(cid)
+	if notFound || chapter.TutorialID != id {
+		global.LOG.Warn("GetTutorialChapterFromParam: chapter not found")
+		return chapter, false
+	}
+	return chapter, true
 }
 
+// GetTutorialLessonFromParam 通过路径参数获取教程课时，课时需属于路径中的教程
+func GetTutorialLessonFromParam(c *gin.Context) (lesson model.TutorialLesson, ok bool) {
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	lid, err2 := strconv.ParseUint(c.Param("lessonID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		global.LOG.Warn("GetTutorialLessonFromParam: id invalid")
+		return lesson, false
+	}
+	lesson, notFound := GetTutorialLessonByID(lid)
+	if notFound || lesson.TutorialID != id {
+		global.LOG.Warn("GetTutorialLessonFromParam: lesson not found")
+		return lesson, false
+	}
+	return lesson, true
+}
+
+// GetTutorialOutline 获取教程的目录，即按位置排列的章节及其课时
+func GetTutorialOutline(tutorialID uint64) (chapters []model.TutorialChapterT) {
+	chapters = make([]model.TutorialChapterT, 0)
+	lessons := make(map[uint64][]model.TutorialLessonT)
+	for _, lesson := range GetTutorialLessons(tutorialID) {
+		lessons[lesson.ChapterID] = append(lessons[lesson.ChapterID], model.TutorialLessonT{
+			ID:       lesson.ID,
+			Title:    lesson.Title,
+			Position: lesson.Position,
+			Version:  lesson.Version})
+	}
+	for _, chapter := range GetTutorialChapters(tutorialID) {
+		item := model.TutorialChapterT{ID: chapter.ID, Title: chapter.Title, Position: chapter.Position, Lessons: lessons[chapter.ID]}
+		if item.Lessons == nil {
+			item.Lessons = make([]model.TutorialLessonT, 0)
+		}
+		chapters = append(chapters, item)
+	}
+	return
+}
+
+// GetLessonProblems 获取课时中嵌入的、当前用户可读的练习题
+func GetLessonProblems(lessonID uint64, c *gin.Context) (problems []model.LessonProblemT) {
+	problems = make([]model.LessonProblemT, 0)
+	for _, id := range GetTutorialLessonProblemIDs(lessonID) {
+		problem, notFound := GetProblemByID(id)
+		if notFound || !JudgeProblemReadPermission(problem, c) {
+			continue
+		}
+		problems = append(problems, model.LessonProblemT{ID: problem.ID, Name: problem.Name, Difficulty: problem.Difficulty})
+	}
+	return
+}
+
+// JudgeLessonProblems 判断课时中嵌入的练习题是否都存在，且对当前用户可读
+func JudgeLessonProblems(problemIDs []uint64, c *gin.Context) bool {
+	for _, id := range problemIDs {
+		problem, notFound := GetProblemByID(id)
+		if notFound || !JudgeProblemReadPermission(problem, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTutorialCommentFromParam 通过路径参数获取教程评论，评论需属于路径中的教程
+func GetTutorialCommentFromParam(c *gin.Context) (comment model.TutorialComment, ok bool) {
+	id, err1 := strconv.ParseUint(c.Param("id"), 10, 64)
+	cid, err2 := strconv.ParseUint(c.Param("commentID"), 10, 64)
+	if err1 != nil || err2 != nil {
+		global.LOG.Warn("GetTutorialCommentFromParam: id invalid")
+		return comment, false
+	}
+	comment, notFound := GetTutorialCommentByID(cid)
+	if notFound || comment.TutorialID != id {
+		global.LOG.Warn("GetTutorialCommentFromParam: comment not found")
+		return comment, false
+	}
+	return comment, true
+}
+
+// GetAnchorSection 获取教程内容中被锚定的部分
+// 锚定到标题时，为该标题至下一个同级或更高级标题之间的内容；锚定到行范围时，为该范围内的行
+func GetAnchorSection(content string, heading string, startLine int, endLine int) (section string, ok bool) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if heading != "" {
+		level := headingLevel(heading)
+		if level == 0 {
+			return "", false
+		}
+		start := -1
+		for i, line := range lines {
+			if start < 0 && strings.TrimSpace(line) == strings.TrimSpace(heading) {
+				start = i
+			} else if start >= 0 && headingLevel(line) > 0 && headingLevel(line) <= level {
+				return strings.Join(lines[start:i], "\n"), true
+			}
+		}
+		if start < 0 {
+			return "", false
+		}
+		return strings.Join(lines[start:], "\n"), true
+	}
+	if startLine < 1 || endLine < startLine || endLine > len(lines) {
+		return "", false
+	}
+	return strings.Join(lines[startLine-1:endLine], "\n"), true
+}
+
+// headingLevel 获取markdown标题的级别，不是标题时返回0
+func headingLevel(line string) int {
+	line = strings.TrimSpace(line)
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0
+	}
+	return level
+}
+
+// HashAnchorSection 计算锚定部分内容的哈希值
+func HashAnchorSection(section string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(section)))
+	return hex.EncodeToString(sum[:])
+}
+
+// BuildTutorialCommentTree 将教程评论组织为树形结构，并判断锚定的评论是否已过时
+func BuildTutorialCommentTree(tutorial model.Tutorial, comments []model.TutorialComment) []model.TutorialCommentT {
+	// 批量获取评论者，并读取当前版本的教程内容
+	userIDs := make([]uint64, 0)
+	for _, comment := range comments {
+		userIDs = append(userIDs, comment.CreatorID)
+	}
+	users := GetUsersByIDs(userIDs)
+	content, err := ReadTutorialVersion(tutorial.ID, tutorial.Version)
+	if err != nil {
+		global.LOG.Warn("BuildTutorialCommentTree: read tutorial file error: ", err)
+	}
+	// 按父评论分组
+	children := make(map[uint64][]model.TutorialComment)
+	for _, comment := range comments {
+		children[comment.ParentID] = append(children[comment.ParentID], comment)
+	}
+	var build func(parentID uint64) []model.TutorialCommentT
+	build = func(parentID uint64) []model.TutorialCommentT {
+		nodes := make([]model.TutorialCommentT, 0)
+		for _, comment := range children[parentID] {
+			node := model.TutorialCommentT{
+				ID:              comment.ID,
+				ParentID:        comment.ParentID,
+				CreatorID:       comment.CreatorID,
+				CreatorName:     users[comment.CreatorID].Name,
+				CreatorAvatar:   users[comment.CreatorID].Avatar,
+				Content:         comment.Content,
+				Version:         comment.Version,
+				AnchorHeading:   comment.AnchorHeading,
+				AnchorStartLine: comment.AnchorStartLine,
+				AnchorEndLine:   comment.AnchorEndLine,
+				CreatedAt:       comment.CreatedAt.Format("2006-01-02 15:04:05"),
+				Replies:         build(comment.ID)}
+			if comment.AnchorHash != "" && comment.Version != tutorial.Version {
+				section, ok := GetAnchorSection(content, comment.AnchorHeading, comment.AnchorStartLine, comment.AnchorEndLine)
+				node.IsOutdated = !ok || HashAnchorSection(section) != comment.AnchorHash
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// 数据库操作
+
 // SaveTutorial 根据信息保存教程
 func SaveTutorial(tutorial *model.Tutorial) (err error) {
 	err = global.DB.Save(tutorial).Error
@@ -34,19 +437,101 @@ func GetTutorialByID(ID uint64) (tutorial model.Tutorial, notFound bool) {
 	}
 }
 
-// UpdateTutorial 根据信息更新教程
-func UpdateTutorial(tutorial *model.Tutorial, q *model.UpdateTutorialQ) (err error) {
-	tutorial.Name, tutorial.Profile, tutorial.Version = q.Name, q.Profile, tutorial.Version+1
-	err = global.DB.Save(tutorial).Error
-	return err
+// UpdateTutorial 根据信息更新教程，教程版本需已由调用者更新，并在同一事务中记录该版本
+// 仅当数据库中的教程版本仍为baseVersion时才会更新，否则返回ErrTutorialVersionConflict，以防止并发更新互相覆盖
+func UpdateTutorial(tutorial *model.Tutorial, baseVersion int, authorID uint64, note string) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Tutorial{}).Where("id = ? AND version = ?", tutorial.ID, baseVersion).
+			Updates(map[string]interface{}{"name": tutorial.Name, "profile": tutorial.Profile, "version": tutorial.Version})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTutorialVersionConflict
+		}
+		version := model.TutorialVersion{TutorialID: tutorial.ID, Version: tutorial.Version, AuthorID: authorID, Note: note}
+		return tx.Create(&version).Error
+	})
 }
 
-// DeleteTutorialByID 根据教程id 删除教程
-func DeleteTutorialByID(ID uint64) (err error) {
-	if err = global.DB.Where("id = ?", ID).Delete(model.Tutorial{}).Error; err != nil {
-		return err
+// RollbackTutorial 保存新版本文件失败时，删除占用的新版本claimed的记录，并将教程回滚到原版本；
+// 教程已被他人更新到更高的版本时不回滚教程，以免覆盖他人的版本
+func RollbackTutorial(origin *model.Tutorial, claimed int) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tutorial_id = ? AND version = ?", origin.ID, claimed).Delete(&model.TutorialVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Tutorial{}).Where("id = ? AND version = ?", origin.ID, claimed).
+			Updates(map[string]interface{}{"name": origin.Name, "profile": origin.Profile, "version": origin.Version}).Error
+	})
+}
+
+// LockTutorial 为用户获取或续期教程的编辑锁，教程被其他用户持有未过期的编辑锁时失败
+func LockTutorial(tutorialID uint64, uid uint64) (ok bool, err error) {
+	now := time.Now()
+	result := global.DB.Model(&model.Tutorial{}).
+		Where("id = ? AND (locker_id = 0 OR locker_id = ? OR lock_expire_time IS NULL OR lock_expire_time < ?)", tutorialID, uid, now).
+		Updates(map[string]interface{}{"locker_id": uid, "lock_expire_time": now.Add(TutorialLockDuration)})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UnlockTutorial 释放教程的编辑锁
+func UnlockTutorial(tutorialID uint64) error {
+	return global.DB.Model(&model.Tutorial{}).Where("id = ?", tutorialID).
+		Updates(map[string]interface{}{"locker_id": 0, "lock_expire_time": nil}).Error
+}
+
+// CreateTutorialVersion 记录教程的一个版本
+func CreateTutorialVersion(version *model.TutorialVersion) error {
+	return global.DB.Create(version).Error
+}
+
+// GetTutorialMainFile 获取教程某个版本的主文档相对路径，该版本为单个文件或没有版本记录时为空
+func GetTutorialMainFile(tutorialID uint64, version int) string {
+	var record model.TutorialVersion
+	if err := global.DB.Where("tutorial_id = ? AND version = ?", tutorialID, version).First(&record).Error; err != nil {
+		return ""
 	}
-	return nil
+	return record.MainFile
+}
+
+// SetTutorialMainFile 记录教程某个版本的主文档相对路径
+func SetTutorialMainFile(tutorialID uint64, version int, mainFile string) error {
+	return global.DB.Model(&model.TutorialVersion{}).Where("tutorial_id = ? AND version = ?", tutorialID, version).
+		Update("main_file", mainFile).Error
+}
+
+// GetTutorialVersions 获取教程的所有历史版本记录，按版本降序排列
+func GetTutorialVersions(tutorialID uint64) (versions []model.TutorialVersion) {
+	versions = make([]model.TutorialVersion, 0)
+	global.DB.Where("tutorial_id = ?", tutorialID).Order("version desc").Find(&versions)
+	return
+}
+
+// DeleteTutorialByID 根据教程id 删除教程，以及教程的所有章节与课时
+func DeleteTutorialByID(ID uint64) (err error) {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		lessonIDs := tx.Model(&model.TutorialLesson{}).Select("id").Where("tutorial_id = ?", ID)
+		if err := tx.Where("lesson_id IN (?)", lessonIDs).Delete(&model.TutorialLessonProblem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("lesson_id IN (?)", lessonIDs).Delete(&model.LessonProgress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tutorial_id = ?", ID).Delete(&model.TutorialLesson{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tutorial_id = ?", ID).Delete(&model.TutorialChapter{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tutorial_id = ?", ID).Delete(&model.TutorialVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tutorial_id = ?", ID).Delete(&model.TutorialComment{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", ID).Delete(model.Tutorial{}).Error
+	})
 }
 
 // GetAllTutorials 查询所有教程
@@ -55,3 +540,130 @@ func GetAllTutorials() (tutorials []model.Tutorial) {
 	global.DB.Find(&tutorials)
 	return tutorials
 }
+
+// GetTutorialChapterByID 根据ID获取教程章节
+func GetTutorialChapterByID(ID uint64) (chapter model.TutorialChapter, notFound bool) {
+	err := global.DB.First(&chapter, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return chapter, true
+	} else if err != nil {
+		global.LOG.Panic("GetTutorialChapterByID: search error")
+		return chapter, true
+	}
+	return chapter, false
+}
+
+// GetTutorialChapters 获取教程的所有章节，按位置排列
+func GetTutorialChapters(tutorialID uint64) (chapters []model.TutorialChapter) {
+	chapters = make([]model.TutorialChapter, 0)
+	global.DB.Where("tutorial_id = ?", tutorialID).Order("position asc, id asc").Find(&chapters)
+	return
+}
+
+// SaveTutorialChapter 保存教程章节
+func SaveTutorialChapter(chapter *model.TutorialChapter) error {
+	return global.DB.Save(chapter).Error
+}
+
+// DeleteTutorialChapter 在一个事务中删除教程章节及其所有课时
+func DeleteTutorialChapter(chapter *model.TutorialChapter) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		lessonIDs := tx.Model(&model.TutorialLesson{}).Select("id").Where("chapter_id = ?", chapter.ID)
+		if err := tx.Where("lesson_id IN (?)", lessonIDs).Delete(&model.TutorialLessonProblem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("lesson_id IN (?)", lessonIDs).Delete(&model.LessonProgress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("chapter_id = ?", chapter.ID).Delete(&model.TutorialLesson{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(chapter).Error
+	})
+}
+
+// GetTutorialLessonByID 根据ID获取教程课时
+func GetTutorialLessonByID(ID uint64) (lesson model.TutorialLesson, notFound bool) {
+	err := global.DB.First(&lesson, ID).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return lesson, true
+	} else if err != nil {
+		global.LOG.Panic("GetTutorialLessonByID: search error")
+		return lesson, true
+	}
+	return lesson, false
+}
+
+// GetTutorialLessons 获取教程的所有课时，按位置排列
+func GetTutorialLessons(tutorialID uint64) (lessons []model.TutorialLesson) {
+	lessons = make([]model.TutorialLesson, 0)
+	global.DB.Where("tutorial_id = ?", tutorialID).Order("position asc, id asc").Find(&lessons)
+	return
+}
+
+// GetTutorialLessonProblemIDs 获取课时中嵌入的练习题ID，按位置排列
+func GetTutorialLessonProblemIDs(lessonID uint64) (problemIDs []uint64) {
+	problemIDs = make([]uint64, 0)
+	global.DB.Model(&model.TutorialLessonProblem{}).Where("lesson_id = ?", lessonID).Order("position asc").Pluck("problem_id", &problemIDs)
+	return
+}
+
+// SaveTutorialLesson 在一个事务中保存教程课时，并以problemIDs替换课时中嵌入的练习题
+func SaveTutorialLesson(lesson *model.TutorialLesson, problemIDs []uint64) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(lesson).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("lesson_id = ?", lesson.ID).Delete(&model.TutorialLessonProblem{}).Error; err != nil {
+			return err
+		}
+		for i, problemID := range problemIDs {
+			problem := model.TutorialLessonProblem{LessonID: lesson.ID, ProblemID: problemID, Position: i}
+			if err := tx.Create(&problem).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteTutorialLesson 在一个事务中删除教程课时及其嵌入的练习题关系与学习进度
+func DeleteTutorialLesson(lesson *model.TutorialLesson) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lesson_id = ?", lesson.ID).Delete(&model.TutorialLessonProblem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("lesson_id = ?", lesson.ID).Delete(&model.LessonProgress{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(lesson).Error
+	})
+}
+
+// GetTutorialCommentByID 根据ID获取教程评论
+func GetTutorialCommentByID(ID uint64) (comment model.TutorialComment, notFound bool) {
+	if err := global.DB.First(&comment, ID).Error; err != nil {
+		return comment, true
+	}
+	return comment, false
+}
+
+// GetTutorialComments 获取教程的所有评论，按时间升序排列
+func GetTutorialComments(tutorialID uint64) (comments []model.TutorialComment) {
+	comments = make([]model.TutorialComment, 0)
+	global.DB.Where("tutorial_id = ?", tutorialID).Order("created_at asc").Find(&comments)
+	return
+}
+
+// DeleteTutorialComment 删除教程评论及其所有回复
+func DeleteTutorialComment(comment model.TutorialComment) error {
+	ids := []uint64{comment.ID}
+	children := make(map[uint64][]uint64)
+	for _, item := range GetTutorialComments(comment.TutorialID) {
+		children[item.ParentID] = append(children[item.ParentID], item.ID)
+	}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return global.DB.Where("id IN ?", ids).Delete(&model.TutorialComment{}).Error
+}
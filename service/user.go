@@ -15,13 +15,18 @@ import (
 func JudgeReadPermission(oid uint64, readable int, creatorID uint64, c *gin.Context) bool {
 	user := utils.SolveUser(c)
 	invitation, notFound := GetInvitationByUserOrg(user.ID, oid)
+	return judgeReadable(readable, creatorID, user.ID, !notFound, invitation.IsAdmin)
+}
+
+// judgeReadable 根据可读权限、用户是否为组织成员与管理员判别可读权限，0为创建者可读，1为组织管理员可读，2为组织成员可读，3为所有人可读
+func judgeReadable(readable int, creatorID uint64, uid uint64, isMember bool, isAdmin bool) bool {
 	switch readable {
 	case 0:
-		return creatorID == user.ID
+		return creatorID == uid
 	case 1:
-		return !notFound && invitation.IsAdmin == true
+		return isMember && isAdmin
 	case 2:
-		return !notFound
+		return isMember
 	case 3:
 		return true
 	default:
@@ -112,3 +117,17 @@ func GetUserInvitation(uid uint64) (invitations []model.Invitation) {
 	global.DB.Model(&model.Invitation{}).Where("user_id = ? AND is_valid = ?", uid, false).Find(&invitations)
 	return
 }
+
+// GetUsersByIDs 批量获取用户，返回用户ID到用户的映射
+func GetUsersByIDs(ids []uint64) (users map[uint64]model.User) {
+	users = make(map[uint64]model.User)
+	if len(ids) == 0 {
+		return
+	}
+	tmp := make([]model.User, 0)
+	global.DB.Where("id IN ?", ids).Find(&tmp)
+	for _, user := range tmp {
+		users[user.ID] = user
+	}
+	return
+}
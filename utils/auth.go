@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"github.com/golang-jwt/jwt"
 	"github.com/phoenix-next/phoenix-server/global"
@@ -41,3 +44,15 @@ func ValidateToken(signedToken string) (id uint64, err error) {
 	}
 	return
 }
+
+// GenerateUnsubscribeToken 生成邮件中退订链接的签名，与登录token不通用
+func GenerateUnsubscribeToken(subscriptionID uint64) string {
+	mac := hmac.New(sha256.New, []byte(global.VP.GetString("server.secret")))
+	mac.Write([]byte("unsubscribe:" + strconv.FormatUint(subscriptionID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateUnsubscribeToken 验证退订链接的签名
+func ValidateUnsubscribeToken(subscriptionID uint64, token string) bool {
+	return hmac.Equal([]byte(GenerateUnsubscribeToken(subscriptionID)), []byte(token))
+}
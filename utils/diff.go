@@ -0,0 +1,13 @@
+package utils
+
+import "github.com/pmezard/go-difflib/difflib"
+
+// DiffText 以unified diff格式比较两段文本，按行比较，context为每处修改前后保留的上下文行数
+func DiffText(from string, to string, fromName string, toName string, context int) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  context})
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"io"
+	"os"
+)
+
+// CopyFile 将src文件的内容复制到dst文件，dst文件已存在时将被覆盖
+func CopyFile(src string, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+// IsIPAllowed 判断IP是否位于允许的IP段中，allowed为以逗号分隔的CIDR或IP，为空表示不限制
+func IsIPAllowed(ip string, allowed string) bool {
+	if strings.TrimSpace(allowed) == "" {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, item := range strings.Split(allowed, ",") {
+		item = strings.TrimSpace(item)
+		if _, network, err := net.ParseCIDR(item); err == nil && network.Contains(addr) {
+			return true
+		}
+		if other := net.ParseIP(item); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"regexp"
+)
+
+// markdown 将Markdown渲染为HTML，支持GFM扩展与LaTeX公式，不输出原始HTML
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough, extension.Linkify, extension.TaskList),
+	goldmark.WithParserOptions(parser.WithInlineParsers(util.Prioritized(mathParser{}, 500))),
+	goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(mathRenderer{}, 500))),
+)
+
+// sanitizer 按白名单过滤渲染结果，图片只能引用本站上传的图片
+var sanitizer = newSanitizer()
+
+func newSanitizer() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardURLs()
+	p.AllowElements("p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre",
+		"ul", "ol", "li", "strong", "em", "del", "code", "table", "thead", "tbody", "tr", "th", "td", "span")
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	// 代码块的语言提示与公式的类型，供客户端高亮代码与渲染公式
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^math (inline|display)$`)).OnElements("span")
+	// 任务列表的复选框
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	// 图片只能引用上传接口返回的路径
+	p.AllowAttrs("src").Matching(regexp.MustCompile(`^(/api/v1/|/)?resource/image/[\w-]+(\.\w+)?$`)).OnElements("img")
+	p.AllowAttrs("alt", "title").OnElements("img")
+	return p
+}
+
+// RenderMarkdown 将Markdown源文本渲染为经过白名单过滤的HTML
+func RenderMarkdown(source string) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return sanitizer.Sanitize(source)
+	}
+	return sanitizer.Sanitize(buf.String())
+}
+
+// kindMath 数学公式节点的类型
+var kindMath = ast.NewNodeKind("Math")
+
+// mathNode 数学公式节点，Display为true表示由$$包围的行间公式
+type mathNode struct {
+	ast.BaseInline
+	Display bool
+	Value   []byte
+}
+
+func (n *mathNode) Kind() ast.NodeKind {
+	return kindMath
+}
+
+func (n *mathNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Value": string(n.Value)}, nil)
+}
+
+// mathParser 解析$...$与$$...$$包围的公式，公式内容不再作为Markdown解析；
+// 行内公式不跨行，行间公式可以跨行，但不超出所在段落
+type mathParser struct{}
+
+// mathFailureKeys 按公式的类型记录最近一次未找到闭合符号的扫描范围，
+// 范围内之后出现的同类型开启符号同样无法闭合，直接跳过以避免重复扫描
+var mathFailureKeys = [3]parser.ContextKey{1: parser.NewContextKey(), 2: parser.NewContextKey()}
+
+// mathFailure 未找到闭合符号的扫描范围，为源文本中的字节偏移
+type mathFailure struct {
+	start, stop int
+}
+
+func (mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (mathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+	// 行内公式的$后不能紧跟空白，以免将金额等普通文本误判为公式
+	if opener == 1 && (len(line) < 2 || line[1] == ' ' || line[1] == '\n') {
+		return nil
+	}
+	l, pos := block.Position()
+	if failure, ok := pc.Get(mathFailureKeys[opener]).(mathFailure); ok && pos.Start > failure.start && pos.Start < failure.stop {
+		return nil
+	}
+	block.Advance(opener)
+	value, stop := make([]byte, 0), pos.Start
+	for {
+		line, segment := block.PeekLine()
+		if line == nil {
+			pc.Set(mathFailureKeys[opener], mathFailure{start: pos.Start, stop: stop})
+			block.SetPosition(l, pos)
+			return nil
+		}
+		for i := 0; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] != '$' || (opener == 2 && (i+1 >= len(line) || line[i+1] != '$')) {
+				continue
+			}
+			if opener == 1 && (i == 0 || line[i-1] == ' ') {
+				continue
+			}
+			value = append(value, line[:i]...)
+			block.Advance(i + opener)
+			return &mathNode{Display: opener == 2, Value: value}
+		}
+		stop = segment.Stop
+		if opener == 1 {
+			pc.Set(mathFailureKeys[opener], mathFailure{start: pos.Start, stop: segment.Stop})
+			block.SetPosition(l, pos)
+			return nil
+		}
+		value = append(value, line...)
+		block.AdvanceLine()
+	}
+}
+
+// mathRenderer 将公式渲染为带类型的span，由客户端使用KaTeX等工具渲染
+type mathRenderer struct{}
+
+func (mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMath, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*mathNode)
+		class := "math inline"
+		if n.Display {
+			class = "math display"
+		}
+		_, _ = w.WriteString(`<span class="` + class + `">`)
+		_, _ = w.Write(util.EscapeHTML(n.Value))
+		_, _ = w.WriteString("</span>")
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{"普通文本", "**bold** and `code`", []string{"<strong>bold</strong>", "<code>code</code>"}, nil},
+		{"script标签", "hi<script>alert(1)</script>", nil, []string{"<script", "alert(1)</script>"}},
+		{"块级script标签", "<script>\nalert(1)\n</script>", nil, []string{"<script"}},
+		{"on*事件属性", `<img src="/resource/image/a.png" onerror="alert(1)">`, nil, []string{"onerror"}},
+		{"链接上的事件属性", `<a href="/x" onclick="alert(1)">x</a>`, nil, []string{"onclick"}},
+		{"javascript链接", "[x](javascript:alert(1))", nil, []string{"javascript:"}},
+		{"大小写混合的javascript链接", "[x](JaVaScRiPt:alert(1))", nil, []string{"href"}},
+		{"外部图片", "![x](https://evil.com/a.png)", nil, []string{"evil.com"}},
+		{"本站图片", "![x](/resource/image/abc.png)", []string{`src="/resource/image/abc.png"`}, nil},
+		{"行内公式", "$a+b$", []string{`<span class="math inline">a+b</span>`}, nil},
+		{"行间公式", "$$x^2$$", []string{`<span class="math display">x^2</span>`}, nil},
+		{"公式中的原始HTML被转义", "$<script>alert(1)</script>$", []string{"&lt;script&gt;"}, []string{"<script"}},
+		{"公式中的事件属性被转义", `$<img src=x onerror=alert(1)>$`, nil, []string{"<img"}},
+		{"未闭合的$按普通文本处理", "price $5 and $x", []string{"price $5 and $x"}, []string{"math"}},
+		{"未闭合的$$按普通文本处理", "$$x^2", []string{"$$x^2"}, []string{"math"}},
+		{"行内公式不跨行", "$a\nb$", nil, []string{"math"}},
+		{"行间公式可以跨行", "$$a\nb$$", []string{`<span class="math display">a`}, nil},
+		{"同一行中的多个公式", "$a$ and $b$", []string{`<span class="math inline">a</span>`, `<span class="math inline">b</span>`}, nil},
+		{"伪造公式类型", `<span class="math inline" onclick="x">a</span>`, nil, []string{"onclick"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMarkdown(tt.source)
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("RenderMarkdown(%q) = %q, want contains %q", tt.source, got, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("RenderMarkdown(%q) = %q, want not contains %q", tt.source, got, s)
+				}
+			}
+		})
+	}
+}
+
+// 大量未闭合的$不应导致重复扫描
+func TestRenderMarkdownUnclosedMath(t *testing.T) {
+	for _, source := range []string{strings.Repeat("$a ", 32000), strings.Repeat("$a\n", 32000), "$$" + strings.Repeat("$a ", 32000)} {
+		start := time.Now()
+		RenderMarkdown(source)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("RenderMarkdown(%q...) took %v", source[:9], elapsed)
+		}
+	}
+}
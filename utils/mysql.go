@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+// IsDuplicateKeyError 判断数据库错误是否为违反唯一索引，用于处理并发插入同一条记录的情况
+func IsDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
@@ -2,57 +2,91 @@ package utils
 
 import (
 	"archive/zip"
+	"errors"
 	"io"
 	"os"
-	"path"
 	"path/filepath"
+	"strings"
 )
 
 // Unzip decompresses a zip file to specified directory.
 // Note that the destination directory don't need to specify the trailing path separator.
-func Unzip(zipPath, dstDir string) error {
+// Entries escaping the destination directory and symbolic links are rejected, and the
+// total decompressed size is limited to maxSize bytes to defend against zip bombs.
+// The archive may contain at most maxEntries entries, and if allowed is not nil, every
+// file entry must satisfy it. Both are checked before anything is extracted.
+func Unzip(zipPath, dstDir string, maxSize int64, maxEntries int, allowed func(name string) bool) error {
 	// open zip file
 	reader, err := zip.OpenReader(zipPath)
 	if err != nil {
 		return err
 	}
 	defer reader.Close()
+	if len(reader.File) > maxEntries {
+		return errors.New("unzip: too many entries")
+	}
+	for _, file := range reader.File {
+		if allowed != nil && !file.FileInfo().IsDir() && !allowed(file.Name) {
+			return errors.New("unzip: file type of " + file.Name + " is not allowed")
+		}
+	}
+	remain := maxSize
 	for _, file := range reader.File {
-		if err := unzipFile(file, dstDir); err != nil {
+		written, err := unzipFile(file, dstDir, remain)
+		if err != nil {
 			return err
 		}
+		remain -= written
 	}
 	return nil
 }
 
-func unzipFile(file *zip.File, dstDir string) error {
+// IsLocalPath reports whether name is a relative path that stays within its parent directory.
+func IsLocalPath(name string) bool {
+	name = filepath.FromSlash(name)
+	if name == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, string(filepath.Separator)) {
+		return false
+	}
+	clean := filepath.Clean(name)
+	return clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+func unzipFile(file *zip.File, dstDir string, remain int64) (int64, error) {
+	// reject unsafe entries
+	if !IsLocalPath(file.Name) {
+		return 0, errors.New("unzip: illegal file path " + file.Name)
+	}
+	if file.Mode()&os.ModeSymlink != 0 {
+		return 0, errors.New("unzip: symbolic link " + file.Name + " is not allowed")
+	}
+
 	// create the directory of file
-	filePath := path.Join(dstDir, file.Name)
+	filePath := filepath.Join(dstDir, filepath.FromSlash(file.Name))
 	if file.FileInfo().IsDir() {
-		if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
-			return err
-		}
-		return nil
+		return 0, os.MkdirAll(filePath, os.ModePerm)
 	}
 	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
-		return err
+		return 0, err
 	}
 
 	// open the file
 	r, err := file.Open()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer r.Close()
 
 	// create the file
 	w, err := os.Create(filePath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer w.Close()
 
-	// save the decompressed file content
-	_, err = io.Copy(w, r)
-	return err
+	// save the decompressed file content, no more than remain bytes
+	written, err := io.Copy(w, io.LimitReader(r, remain+1))
+	if err == nil && written > remain {
+		err = errors.New("unzip: decompressed size exceeds limit")
+	}
+	return written, err
 }
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZip 在临时目录中生成包含给定文件的zip文件
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIsLocalPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"index.md", true},
+		{"images/a.png", true},
+		{"a/../b.md", true},
+		{"", false},
+		{"..", false},
+		{"../evil.md", false},
+		{"a/../../evil.md", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := IsLocalPath(tt.name); got != tt.want {
+			t.Errorf("IsLocalPath(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	isMarkdown := func(name string) bool { return strings.HasSuffix(name, ".md") }
+	tests := []struct {
+		name       string
+		files      map[string]string
+		maxSize    int64
+		maxEntries int
+		allowed    func(string) bool
+		wantErr    string
+	}{
+		{"正常解压", map[string]string{"index.md": "hello", "docs/a.md": "world"}, 100, 10, isMarkdown, ""},
+		{"路径穿越", map[string]string{"../evil.md": "x"}, 100, 10, nil, "illegal file path"},
+		{"绝对路径", map[string]string{"/tmp/evil.md": "x"}, 100, 10, nil, "illegal file path"},
+		{"超过总大小", map[string]string{"a.md": "12345", "b.md": "67890"}, 8, 10, nil, "exceeds limit"},
+		{"恰好等于总大小", map[string]string{"a.md": "12345", "b.md": "678"}, 8, 10, nil, ""},
+		{"条目过多", map[string]string{"a.md": "", "b.md": "", "c.md": ""}, 100, 2, nil, "too many entries"},
+		{"文件类型不允许", map[string]string{"index.md": "", "x.html": "<script>"}, 100, 10, isMarkdown, "not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := t.TempDir()
+			err := Unzip(writeZip(t, tt.files), dst, tt.maxSize, tt.maxEntries, tt.allowed)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unzip() error = %v, want nil", err)
+				}
+				for name, content := range tt.files {
+					data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+					if err != nil || string(data) != content {
+						t.Errorf("file %s = %q, %v, want %q", name, data, err, content)
+					}
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Unzip() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+)
+
+// ZipBytes 将一段数据以指定名称写入zip归档
+func ZipBytes(w *zip.Writer, data []byte, name string) error {
+	f, err := w.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// ZipFile 将一个文件以指定名称写入zip归档
+func ZipFile(w *zip.Writer, src string, name string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := w.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return err
+}